@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"controlSystem/internal/mailer"
+	"controlSystem/internal/models"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	verifyEmailTTL   = 48 * time.Hour
+	resetPasswordTTL = time.Hour
+	// mailTimeout ограничивает фоновую отправку письма.
+	mailTimeout = 30 * time.Second
+)
+
+// AccountConfig — настройки регистрации и входа, связанные с почтой.
+type AccountConfig struct {
+	Mailer mailer.Mailer
+	// FrontendURL — адрес фронтенда для ссылок в письмах.
+	FrontendURL string
+	// RequireEmailVerification запрещает вход до подтверждения почты.
+	RequireEmailVerification bool
+}
+
+var errAccountTokenInvalid = errors.New("ссылка недействительна или устарела")
+
+type EmailInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// issueAccountToken создаёт одноразовый токен и возвращает его открытое значение.
+// Прежние неиспользованные токены того же назначения гасятся.
+func issueAccountToken(db *gorm.DB, userID uint, purpose models.AccountTokenPurpose, ttl time.Duration) (string, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.AccountToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(raw),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	return raw, err
+}
+
+// consumeAccountToken помечает токен использованным и возвращает его.
+// Условие на used_at не даёт использовать одну ссылку дважды параллельно.
+func consumeAccountToken(tx *gorm.DB, raw string, purpose models.AccountTokenPurpose) (models.AccountToken, error) {
+	var t models.AccountToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(raw), purpose).First(&t).Error; err != nil {
+		return t, errAccountTokenInvalid
+	}
+	if t.UsedAt != nil || time.Now().After(t.ExpiresAt) {
+		return t, errAccountTokenInvalid
+	}
+	res := tx.Model(&t).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return t, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return t, errAccountTokenInvalid
+	}
+	return t, nil
+}
+
+func (cfg AccountConfig) link(path, token string) string {
+	return strings.TrimRight(cfg.FrontendURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// sendVerificationEmail отправляет ссылку для подтверждения почты. Ошибка
+// отправки не мешает регистрации — письмо можно запросить повторно.
+func sendVerificationEmail(ctx context.Context, db *gorm.DB, cfg AccountConfig, user models.User) {
+	token, err := issueAccountToken(db, user.ID, models.PurposeVerifyEmail, verifyEmailTTL)
+	if err != nil {
+		log.Println("Failed to issue verification token:", err)
+		return
+	}
+	err = cfg.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение почты",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить адрес почты, перейдите по ссылке:\n%s\n\nСсылка действует %d ч.",
+			user.FullName, cfg.link("/verify-email", token), int(verifyEmailTTL.Hours())),
+	})
+	if err != nil {
+		log.Println("Failed to send verification email:", err)
+	}
+}
+
+// sendResetPasswordEmail отправляет ссылку для сброса пароля.
+func sendResetPasswordEmail(ctx context.Context, db *gorm.DB, cfg AccountConfig, user models.User) {
+	token, err := issueAccountToken(db, user.ID, models.PurposeResetPassword, resetPasswordTTL)
+	if err != nil {
+		log.Println("Failed to issue reset token:", err)
+		return
+	}
+	err = cfg.Mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\nСсылка действует %d мин. Если вы не запрашивали сброс, просто проигнорируйте письмо.",
+			user.FullName, cfg.link("/reset-password", token), int(resetPasswordTTL.Minutes())),
+	})
+	if err != nil {
+		log.Println("Failed to send reset email:", err)
+	}
+}
+
+// sendInBackground отправляет письмо вне запроса: медленный SMTP не должен
+// задерживать ответ.
+func sendInBackground(send func(ctx context.Context)) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		send(ctx)
+	}()
+}
+
+// mailInBackground ищет пользователя по адресу и отправляет ему письмо вне
+// запроса: время ответа не должно выдавать, зарегистрирован ли адрес.
+func mailInBackground(db *gorm.DB, email string, send func(ctx context.Context, user models.User)) {
+	sendInBackground(func(ctx context.Context) {
+		var user models.User
+		if err := db.WithContext(ctx).Where("lower(email) = ?", normalizeEmail(email)).First(&user).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Println("Failed to look up user for email:", err)
+			}
+			return
+		}
+		send(ctx, user)
+	})
+}
+
+// mailThrottled считает запрос письма и отвечает 429, если с этого IP или
+// на этот адрес письма запрашивались слишком часто.
+func mailThrottled(c *gin.Context, db *gorm.DB, email string) bool {
+	keys := mailThrottleKeys(c, email)
+	if wait := throttleWait(db, keys); wait > 0 {
+		tooManyAttempts(c, wait)
+		return true
+	}
+	throttleFail(db, keys)
+	return false
+}
+
+// VerifyEmailHandler подтверждает почту по токену из письма.
+func VerifyEmailHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in VerifyEmailInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			t, err := consumeAccountToken(tx, in.Token, models.PurposeVerifyEmail)
+			if err != nil {
+				return err
+			}
+			return tx.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", t.UserID).
+				Update("email_verified_at", time.Now()).Error
+		})
+		if errors.Is(err, errAccountTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Failed to verify email:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "email verified"})
+	}
+}
+
+// ResendVerificationHandler повторно отправляет письмо с подтверждением.
+// Ни ответ, ни время ответа не зависят от того, есть ли такой адрес, чтобы
+// по нему нельзя было проверять регистрацию.
+func ResendVerificationHandler(db *gorm.DB, cfg AccountConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in EmailInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if mailThrottled(c, db, in.Email) {
+			return
+		}
+
+		mailInBackground(db, in.Email, func(ctx context.Context, user models.User) {
+			if user.EmailVerifiedAt == nil {
+				sendVerificationEmail(ctx, db, cfg, user)
+			}
+		})
+		c.JSON(http.StatusOK, gin.H{"message": "if the address is registered and not verified, an email has been sent"})
+	}
+}
+
+// ForgotPasswordHandler отправляет ссылку для сброса пароля. Как и повторная
+// отправка подтверждения, всегда отвечает одинаково и ограничена по IP и адресу.
+func ForgotPasswordHandler(db *gorm.DB, cfg AccountConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in EmailInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if mailThrottled(c, db, in.Email) {
+			return
+		}
+
+		mailInBackground(db, in.Email, func(ctx context.Context, user models.User) {
+			sendResetPasswordEmail(ctx, db, cfg, user)
+		})
+		c.JSON(http.StatusOK, gin.H{"message": "if the address is registered, an email has been sent"})
+	}
+}
+
+// ResetPasswordHandler задаёт новый пароль по токену из письма и завершает
+// все сессии пользователя. Переход по ссылке заодно подтверждает почту.
+func ResetPasswordHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in ResetPasswordInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		hashed, err := utils.HashPassword(in.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't hash password"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			t, err := consumeAccountToken(tx, in.Token, models.PurposeResetPassword)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", t.UserID).Update("password", hashed).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", t.UserID).
+				Update("email_verified_at", time.Now()).Error; err != nil {
+				return err
+			}
+			return revokeUserSessions(tx, t.UserID)
+		})
+		if errors.Is(err, errAccountTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Failed to reset password:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+    "net/http"
+
+    "controlSystem/internal/middleware"
+    "github.com/gin-gonic/gin"
+)
+
+// resolveActor возвращает ID пользователя из токена. Если клиент всё ещё передаёт
+// actor_id/initiator_id и он не совпадает с токеном, запрос отклоняется —
+// история дефектов должна отражать реального автора действия.
+func resolveActor(c *gin.Context, claimed uint) (uint, bool) {
+    actorID := middleware.CurrentUserID(c)
+    if actorID == 0 {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "нет данных пользователя"})
+        return 0, false
+    }
+    if claimed != 0 && claimed != actorID {
+        c.JSON(http.StatusForbidden, gin.H{"error": "actor_id не совпадает с авторизованным пользователем"})
+        return 0, false
+    }
+    return actorID, true
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const apiKeyPrefix = "csk_"
+
+var apiKeyListSpec = listSpec{
+	Filters:     map[string]string{"user_id": "user_id"},
+	Sortable:    map[string]string{"created_at": "created_at", "last_used_at": "last_used_at"},
+	DefaultSort: "-created_at",
+	Preload:     []string{"User"},
+}
+
+type CreateAPIKeyInput struct {
+	Name      string              `json:"name" binding:"required"`
+	UserID    uint                `json:"user_id" binding:"required"`
+	Scopes    []models.Permission `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+}
+
+// CreateServiceUserHandler создаёт служебного пользователя, к которому
+// привязываются API-ключи. Войти по паролю такой пользователь не может.
+func CreateServiceUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in struct {
+			FullName string      `json:"full_name" binding:"required"`
+			Role     models.Role `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !in.Role.Valid() || in.Role == models.RoleAdmin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		suffix, err := utils.RandomToken(8)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create service user"})
+			return
+		}
+		password, err := utils.RandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create service user"})
+			return
+		}
+		hashed, err := utils.HashPassword(password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't hash password"})
+			return
+		}
+
+		user := models.User{
+			FullName:  strings.TrimSpace(in.FullName),
+			Email:     "service-" + strings.ToLower(suffix) + "@service.invalid",
+			Password:  hashed,
+			Role:      in.Role,
+			IsService: true,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "user", user.ID, "Создание служебного пользователя", "Роль: "+string(user.Role))
+		})
+		if err != nil {
+			log.Println("Failed to create service user:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create service user"})
+			return
+		}
+		c.JSON(http.StatusCreated, user.Profile())
+	}
+}
+
+// CreateAPIKeyHandler выпускает ключ для служебного пользователя. Открытое
+// значение ключа возвращается только в этом ответе.
+func CreateAPIKeyHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in CreateAPIKeyInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if in.ExpiresAt != nil && !in.ExpiresAt.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, in.UserID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		if !user.IsService || user.DeactivatedAt != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "API-ключ можно выдать только активному служебному пользователю"})
+			return
+		}
+		// Ключ не может дать больше, чем позволяет роль его пользователя.
+		scopes := make([]models.Permission, 0, len(in.Scopes))
+		seen := map[models.Permission]bool{}
+		for _, s := range in.Scopes {
+			if !s.Valid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown scope %q", s)})
+				return
+			}
+			if !user.Role.Can(s) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("scope %q is not allowed for role %s", s, user.Role)})
+				return
+			}
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+
+		secret, err := utils.RandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create api key"})
+			return
+		}
+		raw := apiKeyPrefix + secret
+		actorID := middleware.CurrentUserID(c)
+		key := models.APIKey{
+			Name:        strings.TrimSpace(in.Name),
+			Prefix:      raw[:len(apiKeyPrefix)+8],
+			KeyHash:     utils.HashToken(raw),
+			UserID:      user.ID,
+			User:        user.Public(),
+			Scopes:      scopes,
+			ExpiresAt:   in.ExpiresAt,
+			CreatedByID: &actorID,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("User").Create(&key).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, actorID, "api_key", key.ID, "Выпуск API-ключа",
+				fmt.Sprintf("%s (%s), пользователь %d, права: %v", key.Name, key.Prefix, user.ID, scopes))
+		})
+		if err != nil {
+			log.Println("Failed to create api key:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create api key"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"key": raw, "api_key": key})
+	}
+}
+
+func ListAPIKeysHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var keys []models.APIKey
+		if !paginate(c, db.Model(&models.APIKey{}), apiKeyListSpec, &keys) {
+			return
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// RevokeAPIKeyHandler отзывает ключ; запросы с ним сразу начинают получать 401.
+func RevokeAPIKeyHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var key models.APIKey
+		if err := db.Preload("User").First(&key, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		if key.RevokedAt != nil {
+			c.JSON(http.StatusOK, key)
+			return
+		}
+
+		now := time.Now()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&key).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "api_key", key.ID, "Отзыв API-ключа", key.Name+" ("+key.Prefix+")")
+		})
+		if err != nil {
+			log.Println("Failed to revoke api key:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		c.JSON(http.StatusOK, key)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "log"
+    "net/http"
+
+    "controlSystem/internal/middleware"
+    "controlSystem/internal/models"
+    "controlSystem/internal/storage"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+// canManageFile — удалять и заменять вложение может его автор, менеджер или админ.
+func canManageFile(c *gin.Context, file models.DefectFile) bool {
+    switch middleware.CurrentRole(c) {
+    case models.RoleAdmin, models.RoleManager:
+        return true
+    }
+    return file.UploaderID != nil && *file.UploaderID == middleware.CurrentUserID(c)
+}
+
+// DeleteDefectFileHandler убирает вложение из дефекта. Запись удаляется мягко,
+// содержимое в хранилище остаётся — удаление видно в истории дефекта.
+func DeleteDefectFileHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        file, ok := visibleDefectFile(c, db)
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        if !canManageFile(c, file) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "удалить файл может только автор или менеджер"})
+            return
+        }
+
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Delete(&file).Error; err != nil {
+                return err
+            }
+            return tx.Create(&models.DefectHistory{
+                DefectID:   file.DefectID,
+                ActorID:    middleware.CurrentUserID(c),
+                ActionType: "Удаление файла",
+                ActionText: fmt.Sprintf("Удалён файл '%s' (версия %d)", file.FileName, file.Version),
+            }).Error
+        })
+        if err != nil {
+            log.Println("Failed to delete defect file:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete file"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+    }
+}
+
+// ReplaceDefectFileHandler загружает новую версию вложения. Прежняя версия
+// остаётся доступной по своему ID и в /files/:id/versions.
+func ReplaceDefectFileHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        old, ok := visibleDefectFile(c, db)
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        if old.SupersededByID != nil {
+            c.JSON(http.StatusConflict, gin.H{"error": "заменить можно только актуальную версию файла"})
+            return
+        }
+        if !canManageFile(c, old) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "заменить файл может только автор или менеджер"})
+            return
+        }
+
+        fh, err := c.FormFile("file")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан"})
+            return
+        }
+
+        actorID := middleware.CurrentUserID(c)
+        upload, err := storeUpload(c.Request.Context(), db, store, old.DefectID, fh)
+        if err != nil {
+            uploadErrorResponse(c, err)
+            return
+        }
+
+        file := upload.defectFile(old.DefectID, fh.Filename, actorID)
+        file.Version = old.Version + 1
+        file.PreviousVersionID = &old.ID
+
+        err = db.Transaction(func(tx *gorm.DB) error {
+            history := models.DefectHistory{
+                DefectID:   old.DefectID,
+                ActorID:    actorID,
+                ActionType: "Замена файла",
+                ActionText: fmt.Sprintf("Файл '%s' заменён на '%s' (версия %d)", old.FileName, file.FileName, file.Version),
+            }
+            if err := tx.Create(&history).Error; err != nil {
+                return err
+            }
+            file.HistoryID = &history.ID
+            if err := tx.Create(&file).Error; err != nil {
+                return err
+            }
+            // Условие на superseded_by_id защищает от двух одновременных замен одной версии.
+            res := tx.Model(&old).Where(currentFileVersion).Update("superseded_by_id", file.ID)
+            if res.Error != nil {
+                return res.Error
+            }
+            if res.RowsAffected == 0 {
+                return errFileAlreadyReplaced
+            }
+            return nil
+        })
+        if err != nil {
+            removeStored(c.Request.Context(), store, upload)
+            if errors.Is(err, errFileAlreadyReplaced) {
+                c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+                return
+            }
+            log.Println("Failed to replace defect file:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot replace file"})
+            return
+        }
+        c.JSON(http.StatusOK, file)
+    }
+}
+
+// ListDefectFileVersionsHandler возвращает все версии вложения, от первой к последней.
+func ListDefectFileVersionsHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        file, ok := visibleDefectFile(c, db)
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+
+        // Собираем цепочку: назад по PreviousVersionID, затем вперёд по SupersededByID.
+        first := file
+        for first.PreviousVersionID != nil {
+            var prev models.DefectFile
+            if err := db.Unscoped().First(&prev, *first.PreviousVersionID).Error; err != nil {
+                break
+            }
+            first = prev
+        }
+        ids := []uint{first.ID}
+        for cur := first; cur.SupersededByID != nil; {
+            var next models.DefectFile
+            if err := db.Unscoped().First(&next, *cur.SupersededByID).Error; err != nil {
+                break
+            }
+            ids = append(ids, next.ID)
+            cur = next
+        }
+
+        var versions []models.DefectFile
+        if err := db.Preload("Uploader").Where("id IN ?", ids).Order("version asc").Find(&versions).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+            return
+        }
+        c.JSON(http.StatusOK, versions)
+    }
+}
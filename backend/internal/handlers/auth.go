@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -24,13 +26,14 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
+func RegisterHandler(db *gorm.DB, cfg AccountConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var in RegisterInput
 		if err := c.ShouldBindJSON(&in); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		in.Email = normalizeEmail(in.Email)
 
 		role := models.Role(strings.ToLower(in.Role))
 		if role == models.RoleAdmin {
@@ -38,20 +41,33 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		if role == models.RoleEngineer || role == models.RoleManager {
-			if len(in.Code) != 6 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "code must be 6 digits"})
+		// Инженеру и менеджеру код приглашения обязателен; заказчик может
+		// указать его, чтобы сразу попасть в проект.
+		var invite *models.RoleCode
+		if role == models.RoleEngineer || role == models.RoleManager || in.Code != "" {
+			if n := len(strings.TrimSpace(in.Code)); n < 6 || n > inviteCodeLength {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code format"})
 				return
 			}
-			var rc models.RoleCode
-			if err := db.Where("role = ? AND code = ?", role, in.Code).First(&rc).Error; err != nil {
+			keys := roleCodeThrottleKeys(c, in.Email)
+			if wait := throttleWait(db, keys); wait > 0 {
+				recordAuthFailure(db, c, "role_code", in.Email, nil, "locked")
+				tooManyAttempts(c, wait)
+				return
+			}
+			rc, ok := findRoleCode(db, role, in.Code, in.Email)
+			if !ok {
+				throttleFail(db, keys)
+				recordAuthFailure(db, c, "role_code", in.Email, nil, "invalid_code")
 				c.JSON(http.StatusForbidden, gin.H{"error": "invalid code for role"})
 				return
 			}
+			throttleReset(db, keys[1].Key)
+			invite = &rc
 		}
 
 		var exists models.User
-		if err := db.Where("email = ?", in.Email).First(&exists).Error; err == nil {
+		if err := db.Where("lower(email) = ?", in.Email).First(&exists).Error; err == nil {
 			c.JSON(http.StatusConflict, gin.H{"error": "email already registered"})
 			return
 		}
@@ -69,49 +85,83 @@ func RegisterHandler(db *gorm.DB) gin.HandlerFunc {
 			Role:     role,
 		}
 
-		if err := db.Create(&user).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if invite != nil {
+				return redeemRoleCode(tx, *invite, user)
+			}
+			return nil
+		})
+		if errors.Is(err, errInviteCodeUsedUp) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid code for role"})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error: " + err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "registered", "user_id": user.ID})
+		sendInBackground(func(ctx context.Context) {
+			sendVerificationEmail(ctx, db, cfg, user)
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":                     "registered",
+			"user_id":                     user.ID,
+			"email_verification_required": cfg.RequireEmailVerification,
+		})
 	}
 }
 
-func LoginHandler(db *gorm.DB) gin.HandlerFunc {
+func LoginHandler(db *gorm.DB, cfg AccountConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var in LoginInput
 		if err := c.ShouldBindJSON(&in); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		in.Email = normalizeEmail(in.Email)
+
+		keys := loginThrottleKeys(c, in.Email)
+		if wait := throttleWait(db, keys); wait > 0 {
+			recordAuthFailure(db, c, "login", in.Email, nil, "locked")
+			tooManyAttempts(c, wait)
+			return
+		}
 
 		var user models.User
-		if err := db.Where("email = ?", in.Email).First(&user).Error; err != nil {
+		if err := db.Where("lower(email) = ?", in.Email).First(&user).Error; err != nil {
+			throttleFail(db, keys)
+			recordAuthFailure(db, c, "login", in.Email, nil, "invalid_credentials")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
 
 		if err := utils.CheckPasswordHash(user.Password, in.Password); err != nil {
+			throttleFail(db, keys)
+			recordAuthFailure(db, c, "login", in.Email, &user.ID, "invalid_credentials")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
+		throttleReset(db, keys[1].Key)
 
-		token, err := utils.GenerateToken(user.ID, string(user.Role))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create token"})
+		if user.DeactivatedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account deactivated", "code": "account_deactivated"})
+			return
+		}
+		if user.IsService {
+			c.JSON(http.StatusForbidden, gin.H{"error": "service accounts sign in with API keys"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"token": token,
-			"user": gin.H{
-				"id":        user.ID,
-				"full_name": user.FullName,
-				"email":     user.Email,
-				"role":      user.Role,
-			},
-		})
+		if cfg.RequireEmailVerification && user.EmailVerifiedAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email not verified", "code": "email_not_verified"})
+			return
+		}
+
+		startSession(c, db, user)
 	}
 }
 
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "strings"
+    "time"
+
+    "controlSystem/internal/models"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+// fieldChanges собирает изменённые колонки для Updates и их описание для истории.
+type fieldChanges struct {
+    updates map[string]interface{}
+    notes   []string
+}
+
+func newFieldChanges() *fieldChanges {
+    return &fieldChanges{updates: map[string]interface{}{}}
+}
+
+// set регистрирует изменение колонки, если новое значение отличается от старого.
+func (f *fieldChanges) set(column, label string, old, new interface{}) {
+    o, n := formatValue(old), formatValue(new)
+    if o == n {
+        return
+    }
+    f.updates[column] = new
+    f.notes = append(f.notes, fmt.Sprintf("%s: '%s' → '%s'", label, o, n))
+}
+
+func (f *fieldChanges) empty() bool {
+    return len(f.updates) == 0
+}
+
+func (f *fieldChanges) String() string {
+    return strings.Join(f.notes, "; ")
+}
+
+func formatValue(v interface{}) string {
+    switch t := v.(type) {
+    case *time.Time:
+        if t == nil {
+            return "—"
+        }
+        return t.Format("2006-01-02 15:04")
+    case *uint:
+        if t == nil {
+            return "—"
+        }
+        return fmt.Sprint(*t)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+func writeAudit(tx *gorm.DB, actorID uint, entityType string, entityID uint, action, details string) error {
+    return tx.Create(&models.AuditLog{
+        ActorID:    actorID,
+        EntityType: entityType,
+        EntityID:   entityID,
+        Action:     action,
+        Details:    details,
+    }).Error
+}
+
+// respondAudit отдаёт журнал изменений сущности в хронологическом порядке.
+func respondAudit(c *gin.Context, db *gorm.DB, entityType string, entityID uint) {
+    var history []models.AuditLog
+    err := db.Preload("Actor").
+        Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+        Order("created_at asc").Find(&history).Error
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+        return
+    }
+    c.JSON(http.StatusOK, history)
+}
@@ -1,57 +1,88 @@
 package handlers
 
 import (
-	"net/http"
-	"time"
+	"errors"
 	"fmt"
-	"os"
 	"log"
+	"net/http"
+	"time"
 
+	"controlSystem/internal/middleware"
 	"controlSystem/internal/models"
+	"controlSystem/internal/storage"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func CreateDefectHandler(db *gorm.DB) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        defect := models.Defect{
-            Title:       c.PostForm("title"),
-            Description: c.PostForm("description"),
-        }
-
-        projectID := c.PostForm("project_id")
-        initiatorID := c.PostForm("initiator_id")
-        fmt.Sscan(projectID, &defect.ProjectID)
-        fmt.Sscan(initiatorID, &defect.InitiatorID)
-
-        if err := db.Create(&defect).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать дефект"})
-            return
-        }
-
-        c.JSON(http.StatusOK, defect)
-    }
+	return func(c *gin.Context) {
+		defect := models.Defect{
+			Title:       c.PostForm("title"),
+			Description: c.PostForm("description"),
+		}
+
+		var claimedInitiator uint
+		fmt.Sscan(c.PostForm("project_id"), &defect.ProjectID)
+		fmt.Sscan(c.PostForm("initiator_id"), &claimedInitiator)
+
+		initiatorID, ok := resolveActor(c, claimedInitiator)
+		if !ok {
+			return
+		}
+		defect.InitiatorID = initiatorID
+
+		if !projectVisible(c, db, defect.ProjectID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "нет доступа к проекту"})
+			return
+		}
+
+		if err := db.Create(&defect).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать дефект"})
+			return
+		}
+
+		c.JSON(http.StatusOK, defect)
+	}
 }
 
+var defectListSpec = listSpec{
+	Filters: map[string]string{
+		"status":       "status",
+		"project_id":   "project_id",
+		"initiator_id": "initiator_id",
+	},
+	Sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"status":     "status",
+		"project_id": "project_id",
+		"due_date":   "due_date",
+	},
+	DueColumn:   "due_date",
+	DefaultSort: "-created_at",
+	Preload:     []string{"Initiator", "Files", "Files.Uploader"},
+	PreloadWhere: map[string]string{
+		"Files": currentFileVersion,
+	},
+}
 
 func GetDefectsForManager(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var defects []models.Defect
-		if err := db.Preload("Initiator").Preload("Files").Find(&defects, "is_converted = false").Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+		q := db.Where("is_converted = false").Scopes(visibleProjects(c, "project_id"))
+		if !paginate(c, q, defectListSpec, &defects) {
 			return
 		}
 		c.JSON(http.StatusOK, defects)
 	}
 }
 
-
-
 type AssignRequest struct {
 	DefectID   uint   `json:"defect_id" binding:"required"`
 	AssigneeID uint   `json:"assignee_id" binding:"required"`
 	DueDate    string `json:"due_date"`
-	ActorID    uint   `json:"actor_id" binding:"required"`
+	ActorID    uint   `json:"actor_id"`
 }
 
 func AssignAndConvertHandler(db *gorm.DB) gin.HandlerFunc {
@@ -62,11 +93,8 @@ func AssignAndConvertHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		assigneeID := req.AssigneeID
-
-		var defect models.Defect
-		if err := db.First(&defect, req.DefectID).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+		actorID, ok := resolveActor(c, req.ActorID)
+		if !ok {
 			return
 		}
 
@@ -84,55 +112,116 @@ func AssignAndConvertHandler(db *gorm.DB) gin.HandlerFunc {
 			due = &t
 		}
 
-		task := models.Task{
-			Name:            defect.Title,
-			Description:     defect.Description,
-			ProjectID:       defect.ProjectID,
-			CreatorID:       req.ActorID,
-			AssigneeID:      &assigneeID,
-			DueDate:         due,
-			RelatedDefectID: &defect.ID,
-			Status:          "Новая",
-		}
-
-		if err := db.Create(&task).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create task"})
+		if _, ok := defectVisible(c, db, req.DefectID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
 			return
 		}
 
-		defect.IsConverted = true
-		defect.ConvertedToTaskID = &task.ID
-		defect.Status = "В работе"
-		if err := db.Save(&defect).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update defect"})
+		var task models.Task
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			task, err = convertDefectToTask(tx, req.DefectID, req.AssigneeID, actorID, due, middleware.CurrentRole(c))
+			return err
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		case errors.Is(err, models.ErrUnknownStatus), errors.Is(err, models.ErrIllegalTransition), errors.Is(err, models.ErrTransitionForbidden):
+			c.JSON(statusTransitionCode(err), gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, errInvalidAssignee):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		default:
+			log.Println("Failed to convert defect:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot convert defect"})
 			return
 		}
 
-		var actorName string
-		var actor models.User
-		if err := db.First(&actor, req.ActorID).Error; err == nil {
-			actorName = actor.FullName
-		} else {
-			actorName = "неизвестный пользователь"
-		}
+		c.JSON(http.StatusOK, task)
+	}
+}
 
-		db.Create(&models.DefectHistory{
-			DefectID:   defect.ID,
-			ActorID:    req.ActorID,
-			ActionType: "Назначение исполнителя",
-			ActionText: fmt.Sprintf("Назначен исполнитель  (%s)", actorName),
-		})
+// convertDefectToTask создаёт задачу из дефекта, помечает дефект как
+// преобразованный и пишет историю. Строка дефекта блокируется на время
+// транзакции, поэтому повторный или параллельный вызов для уже
+// преобразованного дефекта вернёт существующую задачу, а не создаст дубль.
+func convertDefectToTask(tx *gorm.DB, defectID, assigneeID, actorID uint, due *time.Time, role models.Role) (models.Task, error) {
+	var defect models.Defect
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&defect, defectID).Error; err != nil {
+		return models.Task{}, err
+	}
 
-		c.JSON(http.StatusOK, task)
+	var task models.Task
+	if defect.IsConverted && defect.ConvertedToTaskID != nil {
+		err := tx.First(&task, *defect.ConvertedToTaskID).Error
+		return task, err
+	}
+
+	if err := defect.Status.CheckTransition(models.StatusInProgress, role); err != nil {
+		return models.Task{}, err
+	}
+	if err := checkTargetUser(tx, assigneeID, models.RoleEngineer, errInvalidAssignee); err != nil {
+		return models.Task{}, err
+	}
+
+	task = models.Task{
+		Name:            defect.Title,
+		Description:     defect.Description,
+		ProjectID:       defect.ProjectID,
+		CreatorID:       actorID,
+		AssigneeID:      &assigneeID,
+		DueDate:         due,
+		RelatedDefectID: &defect.ID,
+		Status:          models.StatusNew,
+	}
+	if err := tx.Create(&task).Error; err != nil {
+		return models.Task{}, err
+	}
+	if err := ensureProjectMember(tx, defect.ProjectID, assigneeID, models.ProjectRoleEngineer); err != nil {
+		return models.Task{}, err
+	}
+
+	oldStatus := defect.Status
+	if err := tx.Model(&defect).Updates(map[string]interface{}{
+		"is_converted":         true,
+		"converted_to_task_id": task.ID,
+		"status":               models.StatusInProgress,
+	}).Error; err != nil {
+		return models.Task{}, err
+	}
+
+	actorName := "неизвестный пользователь"
+	var actor models.User
+	if err := tx.First(&actor, actorID).Error; err == nil {
+		actorName = actor.FullName
 	}
+
+	if err := tx.Create(&models.DefectHistory{
+		DefectID:   defect.ID,
+		ActorID:    actorID,
+		ActionType: "Назначение исполнителя",
+		ActionText: fmt.Sprintf("Назначен исполнитель  (%s)", actorName),
+	}).Error; err != nil {
+		return models.Task{}, err
+	}
+	if err := tx.Create(statusHistory(defect.ID, actorID, oldStatus, models.StatusInProgress)).Error; err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
 }
 
 func GetDefectHistory(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defectID := c.Param("id")
-		db.Preload("History.Actor")
+		if _, ok := defectVisible(c, db, defectID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
 		var history []models.DefectHistory
-		if err := db.Where("defect_id = ?", defectID).Order("created_at asc").Find(&history).Error; err != nil {
+		if err := db.Preload("Actor").Where("defect_id = ?", defectID).Order("created_at asc").Find(&history).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 			return
 		}
@@ -142,75 +231,60 @@ func GetDefectHistory(db *gorm.DB) gin.HandlerFunc {
 
 func GetMyTasks(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		assigneeID := c.Query("assignee_id")
-		var tasks []models.Task
-
-		if err := db.
-            Preload("Creator").
-            Preload("Assignee").
-            Preload("RelatedDefect.Project").
-            Preload("RelatedDefect.Initiator").
-            Preload("RelatedDefect.Files").
-            Preload("RelatedDefect.History").
-            Preload("RelatedDefect.History.Actor").
-            Where("assignee_id = ?", assigneeID).
-            Find(&tasks).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
-            return
-        }
-
+		spec := taskListSpec
+		spec.Filters = map[string]string{"status": "status", "project_id": "project_id"}
 
+		var tasks []models.Task
+		q := db.Where("assignee_id = ?", middleware.CurrentUserID(c)).Scopes(visibleProjects(c, "project_id"))
+		if !paginate(c, q, spec, &tasks) {
+			return
+		}
 		c.JSON(http.StatusOK, tasks)
 	}
 }
 
+func TestFileUploadHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defectIDStr := c.PostForm("defect_id")
+		var defectID uint
+		fmt.Sscan(defectIDStr, &defectID)
+		if _, ok := defectVisible(c, db, defectID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
 
-func TestFileUploadHandler(db *gorm.DB) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        if _, err := os.Stat("./uploads"); os.IsNotExist(err) {
-            os.MkdirAll("./uploads", os.ModePerm)
-        }
-
-        defectIDStr := c.PostForm("defect_id")
-        var defectID uint
-        fmt.Sscan(defectIDStr, &defectID)
-
-        file, err := c.FormFile("file")
-        if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан"})
-            return
-        }
-
-        path := "./uploads/" + file.Filename
-        if err := c.SaveUploadedFile(file, path); err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения файла"})
-            return
-        }
-
-        defFile := models.DefectFile{
-            DefectID: defectID,
-            FileName: file.Filename,
-            FilePath: path,
-        }
-
-        if err := db.Create(&defFile).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка записи в БД"})
-            return
-        }
-
-        c.JSON(http.StatusOK, gin.H{
-            "message": "Файл успешно добавлен в БД",
-            "file":    defFile,
-        })
-    }
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Файл не передан"})
+			return
+		}
+
+		upload, err := storeUpload(c.Request.Context(), db, store, defectID, file)
+		if err != nil {
+			uploadErrorResponse(c, err)
+			return
+		}
+
+		defFile := upload.defectFile(defectID, file.Filename, middleware.CurrentUserID(c))
+		if err := db.Create(&defFile).Error; err != nil {
+			removeStored(c.Request.Context(), store, upload)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка записи в БД"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Файл успешно добавлен в БД",
+			"file":    defFile,
+		})
+	}
 }
 
 func UpdateTaskStatus(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		taskID := c.Param("id")
 		var req struct {
-			Status  string `json:"status"`
-			ActorID uint   `json:"actor_id"`
+			Status  models.Status `json:"status" binding:"required"`
+			ActorID uint          `json:"actor_id"`
 		}
 
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -218,33 +292,51 @@ func UpdateTaskStatus(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		actorID, ok := resolveActor(c, req.ActorID)
+		if !ok {
+			return
+		}
+
 		var task models.Task
-		if err := db.First(&task, taskID).Error; err != nil {
+		if err := db.Scopes(visibleProjects(c, "project_id")).First(&task, taskID).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
 			return
 		}
 
-		oldStatus := task.Status
-		task.Status = req.Status
-
-		if err := db.Save(&task).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update task status"})
+		role := middleware.CurrentRole(c)
+		if role == models.RoleEngineer && (task.AssigneeID == nil || *task.AssigneeID != actorID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "задача назначена другому исполнителю"})
+			return
+		}
+		if err := task.Status.CheckTransition(req.Status, role); err != nil {
+			c.JSON(statusTransitionCode(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		if task.RelatedDefectID != nil {
-			history := models.DefectHistory{
-				DefectID:   *task.RelatedDefectID,
-				ActorID:    req.ActorID,
-				ActionType: "Изменение статуса задачи",
-				ActionText: fmt.Sprintf("Статус задачи изменён с '%s' на '%s'", oldStatus, req.Status),
-			}
+		oldStatus := task.Status
+		task.Status = req.Status
 
-			if err := db.Create(&history).Error; err != nil {
-				log.Println("Failed to create defect history:", err)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create defect history"})
-				return
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&task).Error; err != nil {
+				return err
+			}
+			if task.RelatedDefectID != nil {
+				history := models.DefectHistory{
+					DefectID:   *task.RelatedDefectID,
+					ActorID:    actorID,
+					ActionType: "Изменение статуса задачи",
+					ActionText: fmt.Sprintf("Статус задачи изменён с '%s' на '%s'", oldStatus, req.Status),
+				}
+				if err := tx.Create(&history).Error; err != nil {
+					return err
+				}
 			}
+			return syncDefectWithTask(tx, &task, actorID)
+		})
+		if err != nil {
+			log.Println("Failed to update task status:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update task status"})
+			return
 		}
 
 		if task.RelatedDefectID != nil {
@@ -257,95 +349,254 @@ func UpdateTaskStatus(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-func GetAllTasks(db *gorm.DB) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        var tasks []models.Task
-        if err := db.
-            Preload("Creator").
-            Preload("Assignee").
-            Preload("RelatedDefect.Project").
-            Preload("RelatedDefect.Initiator").
-            Preload("RelatedDefect.Files").
-            Preload("RelatedDefect.History").
-            Preload("RelatedDefect.History.Actor").
-
-            Find(&tasks).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
-            return
-        }
-        c.JSON(http.StatusOK, tasks)
-    }
+var taskListSpec = listSpec{
+	Filters: map[string]string{
+		"status":      "status",
+		"project_id":  "project_id",
+		"assignee_id": "assignee_id",
+	},
+	Sortable: map[string]string{
+		"id":          "id",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"status":      "status",
+		"project_id":  "project_id",
+		"assignee_id": "assignee_id",
+		"due_date":    "due_date",
+	},
+	DueColumn:   "due_date",
+	DefaultSort: "-created_at",
+	PreloadWhere: map[string]string{
+		"RelatedDefect.Files": currentFileVersion,
+	},
+	Preload: []string{
+		"Creator",
+		"Assignee",
+		"RelatedDefect.Project",
+		"RelatedDefect.Initiator",
+		"RelatedDefect.Files",
+		"RelatedDefect.Files.Uploader",
+	},
 }
 
+func GetAllTasks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var tasks []models.Task
+		if !paginate(c, db.Scopes(visibleProjects(c, "project_id")), taskListSpec, &tasks) {
+			return
+		}
+		c.JSON(http.StatusOK, tasks)
+	}
+}
 
 type CommentRequest struct {
-    DefectID uint   `json:"defect_id" binding:"required"`
-    ActorID  uint   `json:"actor_id" binding:"required"`
-    Comment  string `json:"comment" binding:"required"`
+	DefectID uint   `json:"defect_id" binding:"required"`
+	ActorID  uint   `json:"actor_id"`
+	Comment  string `json:"comment" binding:"required"`
 }
 
 func AddDefectCommentHandler(db *gorm.DB) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        var req CommentRequest
-        if err := c.ShouldBindJSON(&req); err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-            return
-        }
-
-        history := models.DefectHistory{
-            DefectID:   req.DefectID,
-            ActorID:    req.ActorID,
-            ActionType: "Комментарий",
-            ActionText: req.Comment,
-        }
-
-        if err := db.Create(&history).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить комментарий"})
-            return
-        }
-
-        c.JSON(http.StatusOK, history)
-    }
+	return func(c *gin.Context) {
+		var req CommentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		actorID, ok := resolveActor(c, req.ActorID)
+		if !ok {
+			return
+		}
+		if _, ok := defectVisible(c, db, req.DefectID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
+
+		history := models.DefectHistory{
+			DefectID:   req.DefectID,
+			ActorID:    actorID,
+			ActionType: "Комментарий",
+			ActionText: req.Comment,
+		}
+
+		if err := db.Create(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось сохранить комментарий"})
+			return
+		}
+
+		c.JSON(http.StatusOK, history)
+	}
+}
+
+func AddDefectCommentWithFileHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defectIDStr := c.PostForm("defect_id")
+		actorIDStr := c.PostForm("actor_id")
+		comment := c.PostForm("comment")
+
+		var defectID, claimedActor uint
+		fmt.Sscan(defectIDStr, &defectID)
+		fmt.Sscan(actorIDStr, &claimedActor)
+
+		actorID, ok := resolveActor(c, claimedActor)
+		if !ok {
+			return
+		}
+		if _, ok := defectVisible(c, db, defectID); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
+
+		var fileRecord *models.DefectFile
+		var upload storedUpload
+		if file, err := c.FormFile("file"); err == nil {
+			upload, err = storeUpload(c.Request.Context(), db, store, defectID, file)
+			if err != nil {
+				uploadErrorResponse(c, err)
+				return
+			}
+			df := upload.defectFile(defectID, file.Filename, actorID)
+			fileRecord = &df
+		}
+
+		history := models.DefectHistory{
+			DefectID:   defectID,
+			ActorID:    actorID,
+			ActionType: "Комментарий",
+			ActionText: comment,
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&history).Error; err != nil {
+				return err
+			}
+			if fileRecord != nil {
+				fileRecord.HistoryID = &history.ID
+				return tx.Create(fileRecord).Error
+			}
+			return nil
+		})
+		if err != nil {
+			removeStored(c.Request.Context(), store, upload)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save comment"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"historyItem": history, "file": fileRecord})
+	}
 }
 
-func AddDefectCommentWithFileHandler(db *gorm.DB) gin.HandlerFunc {
-    return func(c *gin.Context) {
-        defectIDStr := c.PostForm("defect_id")
-        actorIDStr := c.PostForm("actor_id")
-        comment := c.PostForm("comment")
-
-        var defectID, actorID uint
-        fmt.Sscan(defectIDStr, &defectID)
-        fmt.Sscan(actorIDStr, &actorID)
-
-        history := models.DefectHistory{
-            DefectID:   defectID,
-            ActorID:    actorID,
-            ActionType: "Комментарий",
-            ActionText: comment,
-        }
-        if err := db.Create(&history).Error; err != nil {
-            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save comment"})
-            return
-        }
-
-        var fileRecord *models.DefectFile
-        file, err := c.FormFile("file")
-        if err == nil {
-            path := "./uploads/" + file.Filename
-            if err := c.SaveUploadedFile(file, path); err != nil {
-                c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save file"})
-                return
-            }
-            df := models.DefectFile{
-                DefectID: defectID,
-                FileName: file.Filename,
-                FilePath: path,
-            }
-            db.Create(&df)
-            fileRecord = &df
-        }
-
-        c.JSON(http.StatusOK, gin.H{"historyItem": history, "file": fileRecord})
-    }
+func UpdateDefectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Title       *string `json:"title"`
+			Description *string `json:"description"`
+			DueDate     *string `json:"due_date"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		defect, ok := defectVisible(c, db, c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
+
+		changes := newFieldChanges()
+		if input.Title != nil {
+			changes.set("title", "Название", defect.Title, *input.Title)
+		}
+		if input.Description != nil {
+			changes.set("description", "Описание", defect.Description, *input.Description)
+		}
+		if input.DueDate != nil {
+			var due *time.Time
+			if *input.DueDate != "" {
+				t, err := parseDate(*input.DueDate)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due_date format"})
+					return
+				}
+				due = &t
+			}
+			changes.set("due_date", "Срок", defect.DueDate, due)
+		}
+		if changes.empty() {
+			c.JSON(http.StatusOK, defect)
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&defect).Updates(changes.updates).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.DefectHistory{
+				DefectID:   defect.ID,
+				ActorID:    middleware.CurrentUserID(c),
+				ActionType: "Изменение дефекта",
+				ActionText: changes.String(),
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update defect"})
+			return
+		}
+		c.JSON(http.StatusOK, defect)
+	}
+}
+
+func DeleteDefectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defect, ok := defectVisible(c, db, c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&defect).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.DefectHistory{
+				DefectID:   defect.ID,
+				ActorID:    middleware.CurrentUserID(c),
+				ActionType: "Удаление дефекта",
+				ActionText: defect.Title,
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete defect"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	}
+}
+
+func RestoreDefectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var defect models.Defect
+		if err := db.Unscoped().Scopes(visibleProjects(c, "project_id")).Where("deleted_at IS NOT NULL").First(&defect, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "deleted defect not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Model(&defect).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.DefectHistory{
+				DefectID:   defect.ID,
+				ActorID:    middleware.CurrentUserID(c),
+				ActionType: "Восстановление дефекта",
+				ActionText: defect.Title,
+			}).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot restore defect"})
+			return
+		}
+		defect.DeletedAt = gorm.DeletedAt{}
+		c.JSON(http.StatusOK, defect)
+	}
 }
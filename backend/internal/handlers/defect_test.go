@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"controlSystem/internal/models"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB поднимает чистую in-memory SQLite с таблицами tables.
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	// у каждого соединения своя in-memory база — держим ровно одно
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// newConversionDB — таблицы, которые трогает конвертация дефекта в задачу.
+func newConversionDB(t *testing.T) *gorm.DB {
+	return newTestDB(t,
+		&models.User{}, &models.Project{}, &models.ProjectMember{},
+		&models.Defect{}, &models.DefectHistory{}, &models.Task{},
+	)
+}
+
+type conversionFixture struct {
+	db                *gorm.DB
+	manager, engineer models.User
+	defect            models.Defect
+}
+
+// newConversionFixture — отдельная база с менеджером, инженером, проектом
+// и новым дефектом в нём.
+func newConversionFixture(t *testing.T) conversionFixture {
+	t.Helper()
+	f := conversionFixture{
+		db:       newConversionDB(t),
+		manager:  models.User{FullName: "Менеджер", Email: "manager@example.com", Role: models.RoleManager},
+		engineer: models.User{FullName: "Инженер", Email: "engineer@example.com", Role: models.RoleEngineer},
+	}
+	if err := f.db.Create(&f.manager).Error; err != nil {
+		t.Fatalf("seed manager: %v", err)
+	}
+	if err := f.db.Create(&f.engineer).Error; err != nil {
+		t.Fatalf("seed engineer: %v", err)
+	}
+	project := models.Project{Name: "Объект", ManagerID: f.manager.ID}
+	if err := f.db.Create(&project).Error; err != nil {
+		t.Fatalf("seed project: %v", err)
+	}
+	f.defect = models.Defect{
+		Title:       "Трещина в стене",
+		ProjectID:   project.ID,
+		InitiatorID: f.manager.ID,
+		Status:      models.StatusNew,
+	}
+	if err := f.db.Create(&f.defect).Error; err != nil {
+		t.Fatalf("seed defect: %v", err)
+	}
+	return f
+}
+
+func (f conversionFixture) convert(assigneeID uint) (models.Task, error) {
+	var task models.Task
+	err := f.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = convertDefectToTask(tx, f.defect.ID, assigneeID, f.manager.ID, nil, models.RoleManager)
+		return err
+	})
+	return task, err
+}
+
+// assertUnconverted проверяет, что у дефекта нет задачи и он не тронут.
+func (f conversionFixture) assertUnconverted(t *testing.T) {
+	t.Helper()
+	var tasks int64
+	f.db.Unscoped().Model(&models.Task{}).Where("related_defect_id = ?", f.defect.ID).Count(&tasks)
+	if tasks != 0 {
+		t.Fatalf("task left behind: %d rows", tasks)
+	}
+	var defect models.Defect
+	if err := f.db.First(&defect, f.defect.ID).Error; err != nil {
+		t.Fatalf("reload defect: %v", err)
+	}
+	if defect.IsConverted || defect.ConvertedToTaskID != nil || defect.Status != models.StatusNew {
+		t.Fatalf("defect changed: converted=%v task=%v status=%s",
+			defect.IsConverted, defect.ConvertedToTaskID, defect.Status)
+	}
+	if n := countHistory(t, f.db, f.defect.ID, "Назначение исполнителя"); n != 0 {
+		t.Fatalf("assignment history written: %d rows", n)
+	}
+}
+
+func countHistory(t *testing.T, db *gorm.DB, defectID uint, actionType string) int64 {
+	t.Helper()
+	var n int64
+	if err := db.Model(&models.DefectHistory{}).
+		Where("defect_id = ? AND action_type = ?", defectID, actionType).
+		Count(&n).Error; err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	return n
+}
+
+func TestConvertDefectToTaskIsIdempotent(t *testing.T) {
+	f := newConversionFixture(t)
+
+	first, err := f.convert(f.engineer.ID)
+	if err != nil {
+		t.Fatalf("first conversion: %v", err)
+	}
+	second, err := f.convert(f.engineer.ID)
+	if err != nil {
+		t.Fatalf("second conversion: %v", err)
+	}
+	if first.ID == 0 || second.ID != first.ID {
+		t.Fatalf("second conversion returned task %d, want %d", second.ID, first.ID)
+	}
+
+	var tasks int64
+	f.db.Model(&models.Task{}).Where("related_defect_id = ?", f.defect.ID).Count(&tasks)
+	if tasks != 1 {
+		t.Fatalf("tasks for defect = %d, want 1", tasks)
+	}
+	if n := countHistory(t, f.db, f.defect.ID, "Назначение исполнителя"); n != 1 {
+		t.Fatalf("assignment history rows = %d, want 1", n)
+	}
+}
+
+func TestConvertDefectToTaskRollsBackOnError(t *testing.T) {
+	f := newConversionFixture(t)
+
+	// ломаем обновление дефекта, которое идёт уже после создания задачи
+	injected := errors.New("injected failure")
+	if err := f.db.Callback().Update().Before("gorm:update").Register("test:fail_defect_update", func(tx *gorm.DB) {
+		if tx.Statement.Table == "defects" {
+			tx.AddError(injected)
+		}
+	}); err != nil {
+		t.Fatalf("register callback: %v", err)
+	}
+
+	if _, err := f.convert(f.engineer.ID); !errors.Is(err, injected) {
+		t.Fatalf("conversion error = %v, want injected failure", err)
+	}
+	f.assertUnconverted(t)
+}
+
+func TestConvertDefectToTaskRejectsInvalidAssignee(t *testing.T) {
+	f := newConversionFixture(t)
+	now := time.Now()
+	retired := models.User{FullName: "Уволен", Email: "retired@example.com", Role: models.RoleEngineer, DeactivatedAt: &now}
+	if err := f.db.Create(&retired).Error; err != nil {
+		t.Fatalf("seed retired engineer: %v", err)
+	}
+
+	cases := map[string]uint{
+		"missing user":         9999,
+		"not an engineer":      f.manager.ID,
+		"deactivated engineer": retired.ID,
+	}
+	for name, assigneeID := range cases {
+		if _, err := f.convert(assigneeID); !errors.Is(err, errInvalidAssignee) {
+			t.Fatalf("%s: conversion error = %v, want errInvalidAssignee", name, err)
+		}
+		f.assertUnconverted(t)
+	}
+}
+
+func TestConvertDefectToTaskWritesHistory(t *testing.T) {
+	f := newConversionFixture(t)
+
+	task, err := f.convert(f.engineer.ID)
+	if err != nil {
+		t.Fatalf("conversion: %v", err)
+	}
+
+	var history []models.DefectHistory
+	if err := f.db.Where("defect_id = ?", f.defect.ID).Order("id").Find(&history).Error; err != nil {
+		t.Fatalf("load history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history rows = %d, want 2", len(history))
+	}
+	if history[0].ActionType != "Назначение исполнителя" || history[0].ActorID != f.manager.ID {
+		t.Fatalf("unexpected assignment entry %+v", history[0])
+	}
+	if history[1].ActionType != "Изменение статуса дефекта" || history[1].ActorID != f.manager.ID {
+		t.Fatalf("unexpected status entry %+v", history[1])
+	}
+
+	var defect models.Defect
+	if err := f.db.First(&defect, f.defect.ID).Error; err != nil {
+		t.Fatalf("reload defect: %v", err)
+	}
+	if !defect.IsConverted || defect.ConvertedToTaskID == nil || *defect.ConvertedToTaskID != task.ID {
+		t.Fatalf("defect not linked to task %d", task.ID)
+	}
+	if defect.Status != models.StatusInProgress {
+		t.Fatalf("defect status = %s, want %s", defect.Status, models.StatusInProgress)
+	}
+}
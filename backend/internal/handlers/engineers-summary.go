@@ -18,7 +18,7 @@ type EngineerSummary struct {
 func EngineersSummaryHandler(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
         var tasks []models.Task
-        if err := db.Preload("Assignee").Find(&tasks).Error; err != nil {
+        if err := db.Scopes(visibleProjects(c, "project_id")).Preload("Assignee").Find(&tasks).Error; err != nil {
             c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch tasks"})
             return
         }
@@ -38,7 +38,7 @@ func EngineersSummaryHandler(db *gorm.DB) gin.HandlerFunc {
                 }
             }
             summaryMap[name].Total++
-            if task.Status == "Закрыта" {
+            if task.Status == models.StatusClosed {
                 summaryMap[name].Closed++
             }
         }
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+    "archive/zip"
+    "context"
+    "encoding/csv"
+    "fmt"
+    "io"
+    "log"
+    "mime"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "controlSystem/internal/models"
+    "controlSystem/internal/storage"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+// ExportDefectFilesHandler отдаёт ZIP со всеми актуальными вложениями дефекта.
+func ExportDefectFilesHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        defect, ok := defectVisible(c, db, c.Param("id"))
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "defect not found"})
+            return
+        }
+
+        var defects []models.Defect
+        if err := exportQuery(db).Where("id = ?", defect.ID).Find(&defects).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+            return
+        }
+        writeFilesZip(c, store, fmt.Sprintf("defect-%d.zip", defect.ID), defects)
+    }
+}
+
+// ExportProjectFilesHandler отдаёт ZIP с вложениями всех дефектов проекта.
+func ExportProjectFilesHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        projectID, err := strconv.Atoi(c.Param("id"))
+        if err != nil || !projectVisible(c, db, uint(projectID)) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+
+        var defects []models.Defect
+        if err := exportQuery(db).Where("project_id = ?", projectID).Order("id asc").Find(&defects).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+            return
+        }
+        writeFilesZip(c, store, fmt.Sprintf("project-%d.zip", projectID), defects)
+    }
+}
+
+func exportQuery(db *gorm.DB) *gorm.DB {
+    return db.Preload("Files", func(q *gorm.DB) *gorm.DB {
+        return q.Where(currentFileVersion).Order("id asc")
+    }).Preload("Files.Uploader")
+}
+
+// writeFilesZip пишет архив прямо в ответ: папка на каждый дефект и manifest.csv
+// в корне. Файл, которого нет в хранилище, пропускается и помечается в манифесте.
+func writeFilesZip(c *gin.Context, store storage.Storage, name string, defects []models.Defect) {
+    c.Header("Content-Type", "application/zip")
+    c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+    c.Status(http.StatusOK)
+
+    zw := zip.NewWriter(c.Writer)
+    defer func() {
+        if err := zw.Close(); err != nil {
+            log.Println("Failed to finish zip export:", err)
+        }
+    }()
+
+    manifest := [][]string{{"defect_id", "defect_title", "defect_status", "file", "uploader", "uploaded_at", "size", "sha256", "note"}}
+    ctx := c.Request.Context()
+
+    for _, d := range defects {
+        folder := fmt.Sprintf("%d_%s", d.ID, zipSafeName(d.Title))
+        used := map[string]int{}
+        if len(d.Files) == 0 {
+            manifest = append(manifest, []string{
+                strconv.FormatUint(uint64(d.ID), 10), d.Title, string(d.Status), "", "", "", "", "", "нет вложений",
+            })
+        }
+
+        for _, f := range d.Files {
+            entry := folder + "/" + uniqueZipName(used, zipSafeName(f.FileName))
+            uploader := ""
+            if f.Uploader != nil {
+                uploader = f.Uploader.FullName
+            }
+            row := []string{
+                strconv.FormatUint(uint64(d.ID), 10), d.Title, string(d.Status), entry, uploader,
+                f.CreatedAt.Format("2006-01-02 15:04"), strconv.FormatInt(f.Size, 10), f.Checksum, "",
+            }
+
+            if err := copyToZip(ctx, zw, store, f, entry); err != nil {
+                log.Println("Failed to add file to zip:", err)
+                row[len(row)-1] = "файл недоступен"
+            }
+            manifest = append(manifest, row)
+        }
+    }
+
+    w, err := zw.Create("manifest.csv")
+    if err != nil {
+        log.Println("Failed to add manifest to zip:", err)
+        return
+    }
+    // BOM, чтобы Excel открыл кириллицу без ручного выбора кодировки.
+    io.WriteString(w, "\uFEFF")
+    cw := csv.NewWriter(w)
+    cw.WriteAll(manifest)
+}
+
+func copyToZip(ctx context.Context, zw *zip.Writer, store storage.Storage, f models.DefectFile, entry string) error {
+    rc, err := store.Get(ctx, f.FilePath)
+    if err != nil {
+        return err
+    }
+    defer rc.Close()
+
+    w, err := zw.CreateHeader(&zip.FileHeader{Name: entry, Method: zip.Deflate, Modified: f.CreatedAt})
+    if err != nil {
+        return err
+    }
+    _, err = io.Copy(w, rc)
+    return err
+}
+
+// zipSafeName убирает из имени разделители путей и управляющие символы.
+func zipSafeName(name string) string {
+    name = strings.Map(func(r rune) rune {
+        switch {
+        case r == '/' || r == '\\' || r == ':' || r < 0x20:
+            return '_'
+        }
+        return r
+    }, strings.TrimSpace(name))
+    name = strings.Trim(name, ".")
+    if r := []rune(name); len(r) > 80 {
+        name = string(r[:80])
+    }
+    if name == "" {
+        return "file"
+    }
+    return name
+}
+
+// uniqueZipName добавляет суффикс, если в папке уже есть файл с таким именем.
+func uniqueZipName(used map[string]int, name string) string {
+    n := used[name]
+    used[name]++
+    if n == 0 {
+        return name
+    }
+    ext := ""
+    if i := strings.LastIndex(name, "."); i > 0 {
+        name, ext = name[:i], name[i:]
+    }
+    return fmt.Sprintf("%s (%d)%s", name, n+1, ext)
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "log"
+    "mime"
+    "mime/multipart"
+    "net/http"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "controlSystem/internal/models"
+    "controlSystem/internal/storage"
+    "controlSystem/internal/utils"
+    "github.com/gabriel-vasile/mimetype"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+const (
+    maxUploadSize   = 20 << 20
+    presignedURLTTL = 15 * time.Minute
+)
+
+// allowedUploadTypes — MIME-типы, которые можно прикладывать к дефектам.
+// Тип определяется по содержимому файла, а не по расширению или заголовку клиента.
+var allowedUploadTypes = map[string]bool{
+    "image/jpeg":      true,
+    "image/png":       true,
+    "image/webp":      true,
+    "application/pdf": true,
+    "text/plain":      true,
+    "application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       true,
+}
+
+// currentFileVersion — условие для выборки только актуальных версий вложений.
+const currentFileVersion = "superseded_by_id IS NULL"
+
+var (
+    errFileTooLarge        = errors.New("файл слишком большой")
+    errFileTypeNotAllowed  = errors.New("недопустимый тип файла")
+    errFileAlreadyReplaced = errors.New("файл уже заменён другой версией")
+)
+
+// imageVariantTypes — типы, для которых при загрузке строятся thumbnail и preview.
+var imageVariantTypes = map[string]bool{
+    "image/jpeg": true,
+    "image/png":  true,
+}
+
+// duplicateFileError — в дефект уже загружен файл с таким же содержимым.
+type duplicateFileError struct {
+    Existing models.DefectFile
+}
+
+func (e *duplicateFileError) Error() string {
+    return "такой файл уже приложен к дефекту"
+}
+
+// storedUpload — ключи загруженного файла и его уменьшенных копий в хранилище.
+type storedUpload struct {
+    Key          string
+    ThumbnailKey string
+    PreviewKey   string
+    ContentType  string
+    Size         int64
+    Checksum     string
+}
+
+func (u storedUpload) defectFile(defectID uint, fileName string, uploaderID uint) models.DefectFile {
+    return models.DefectFile{
+        DefectID:      defectID,
+        FileName:      originalFileName(fileName),
+        FilePath:      u.Key,
+        ThumbnailPath: u.ThumbnailKey,
+        PreviewPath:   u.PreviewKey,
+        Size:          u.Size,
+        MimeType:      u.ContentType,
+        Checksum:      u.Checksum,
+        UploaderID:    &uploaderID,
+    }
+}
+
+// storeUpload проверяет размер и тип загруженного файла и кладёт его
+// в хранилище под случайным ключом. Имя файла от клиента в ключ не попадает.
+// Если в дефекте уже есть файл с тем же SHA-256, возвращается *duplicateFileError.
+// Для фотографий сразу строятся уменьшенные копии; если картинку не удалось
+// разобрать, файл всё равно сохраняется, просто без копий.
+func storeUpload(ctx context.Context, db *gorm.DB, store storage.Storage, defectID uint, fh *multipart.FileHeader) (storedUpload, error) {
+    if fh.Size > maxUploadSize {
+        return storedUpload{}, errFileTooLarge
+    }
+
+    src, err := fh.Open()
+    if err != nil {
+        return storedUpload{}, err
+    }
+    defer src.Close()
+
+    mtype, err := mimetype.DetectReader(src)
+    if err != nil {
+        return storedUpload{}, err
+    }
+    contentType := strings.SplitN(mtype.String(), ";", 2)[0]
+    if !allowedUploadTypes[contentType] {
+        return storedUpload{}, errFileTypeNotAllowed
+    }
+    if _, err := src.Seek(0, io.SeekStart); err != nil {
+        return storedUpload{}, err
+    }
+
+    hash := sha256.New()
+    size, err := io.Copy(hash, src)
+    if err != nil {
+        return storedUpload{}, err
+    }
+    checksum := hex.EncodeToString(hash.Sum(nil))
+    if _, err := src.Seek(0, io.SeekStart); err != nil {
+        return storedUpload{}, err
+    }
+
+    var existing models.DefectFile
+    if err := db.Where("defect_id = ? AND checksum = ?", defectID, checksum).Where(currentFileVersion).First(&existing).Error; err == nil {
+        return storedUpload{}, &duplicateFileError{Existing: existing}
+    }
+
+    key, err := newStorageKey()
+    if err != nil {
+        return storedUpload{}, err
+    }
+    if err := store.Put(ctx, key, src, size, contentType); err != nil {
+        return storedUpload{}, err
+    }
+    up := storedUpload{Key: key, ContentType: contentType, Size: size, Checksum: checksum}
+
+    if imageVariantTypes[contentType] {
+        if _, err := src.Seek(0, io.SeekStart); err != nil {
+            return up, nil
+        }
+        thumb, preview, err := utils.ImageVariants(src, contentType)
+        if err != nil {
+            log.Println("Failed to build image variants:", err)
+            return up, nil
+        }
+        if err := store.Put(ctx, key+"_thumb", bytes.NewReader(thumb), int64(len(thumb)), contentType); err != nil {
+            removeStored(ctx, store, up)
+            return storedUpload{}, err
+        }
+        up.ThumbnailKey = key + "_thumb"
+        if err := store.Put(ctx, key+"_preview", bytes.NewReader(preview), int64(len(preview)), contentType); err != nil {
+            removeStored(ctx, store, up)
+            return storedUpload{}, err
+        }
+        up.PreviewKey = key + "_preview"
+    }
+    return up, nil
+}
+
+func newStorageKey() (string, error) {
+    b := make([]byte, 16)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+func removeStored(ctx context.Context, store storage.Storage, up storedUpload) {
+    for _, key := range []string{up.Key, up.ThumbnailKey, up.PreviewKey} {
+        if key == "" {
+            continue
+        }
+        if err := store.Delete(ctx, key); err != nil {
+            log.Println("Failed to remove stored file:", err)
+        }
+    }
+}
+
+// originalFileName оставляет от имени клиента только последний компонент —
+// оно используется лишь для отображения и Content-Disposition.
+func originalFileName(name string) string {
+    name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+    if name == "." || name == "/" {
+        return "file"
+    }
+    return name
+}
+
+func uploadErrorResponse(c *gin.Context, err error) {
+    var dup *duplicateFileError
+    switch {
+    case errors.As(err, &dup):
+        c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "file": dup.Existing})
+    case errors.Is(err, errFileTooLarge):
+        c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+    case errors.Is(err, errFileTypeNotAllowed):
+        c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+    default:
+        log.Println("Failed to store upload:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения файла"})
+    }
+}
+
+// visibleDefectFile загружает запись о вложении, если пользователь видит дефект, к которому оно приложено.
+func visibleDefectFile(c *gin.Context, db *gorm.DB) (models.DefectFile, bool) {
+    var file models.DefectFile
+    if err := db.First(&file, c.Param("id")).Error; err != nil {
+        return file, false
+    }
+    _, ok := defectVisible(c, db, file.DefectID)
+    return file, ok
+}
+
+// variantKey возвращает ключ запрошенной копии файла (?variant=thumb|preview).
+func variantKey(file models.DefectFile, variant string) (string, bool) {
+    switch variant {
+    case "":
+        return file.FilePath, true
+    case "thumb":
+        return file.ThumbnailPath, file.ThumbnailPath != ""
+    case "preview":
+        return file.PreviewPath, file.PreviewPath != ""
+    }
+    return "", false
+}
+
+// DownloadDefectFileHandler отдаёт вложение через backend, проверив доступ к дефекту.
+// ?variant=thumb|preview возвращает уменьшенную копию изображения.
+func DownloadDefectFileHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        file, ok := visibleDefectFile(c, db)
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        key, ok := variantKey(file, c.Query("variant"))
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File variant not found"})
+            return
+        }
+
+        ctx := c.Request.Context()
+        info, err := store.Stat(ctx, key)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        rc, err := store.Get(ctx, key)
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+        defer rc.Close()
+
+        contentType := info.ContentType
+        if contentType == "" {
+            contentType = file.MimeType
+        }
+        if contentType == "" {
+            contentType = "application/octet-stream"
+        }
+        // Копии изображений показываются прямо в интерфейсе, оригинал скачивается.
+        disposition := "attachment"
+        if key != file.FilePath {
+            disposition = "inline"
+        }
+        c.DataFromReader(http.StatusOK, info.Size, contentType, rc, map[string]string{
+            "Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": file.FileName}),
+            "X-Content-Type-Options": "nosniff",
+        })
+    }
+}
+
+// DefectFileURLHandler выдаёт временную прямую ссылку на вложение, если
+// хранилище это поддерживает (S3). Для локального диска ответ 501 —
+// клиент должен скачивать через DownloadDefectFileHandler.
+func DefectFileURLHandler(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        file, ok := visibleDefectFile(c, db)
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+            return
+        }
+
+        key, ok := variantKey(file, c.Query("variant"))
+        if !ok {
+            c.JSON(http.StatusNotFound, gin.H{"error": "File variant not found"})
+            return
+        }
+
+        url, err := store.PresignedURL(c.Request.Context(), key, presignedURLTTL, file.FileName)
+        if errors.Is(err, storage.ErrPresignNotSupported) {
+            c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+            return
+        }
+        if err != nil {
+            log.Println("Failed to presign file url:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot create file url"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"url": url, "expires_in": int(presignedURLTTL.Seconds())})
+    }
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	inviteCodeLength   = 12
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // без 0/O и 1/I
+	defaultInviteTTL   = 7 * 24 * time.Hour
+)
+
+var errInviteCodeUsedUp = errors.New("invitation code is no longer valid")
+
+var inviteListSpec = listSpec{
+	Filters:     map[string]string{"role": "role", "project_id": "project_id", "email": "email"},
+	Sortable:    map[string]string{"created_at": "created_at", "expires_at": "expires_at"},
+	DefaultSort: "-created_at",
+	Preload:     []string{"Project", "CreatedBy", "Redemptions", "Redemptions.User"},
+}
+
+type CreateInviteInput struct {
+	Role models.Role `json:"role" binding:"required"`
+	// MaxUses: по умолчанию 1 (одноразовый код), 0 — без ограничения.
+	MaxUses *int `json:"max_uses"`
+	// ExpiresAt: по умолчанию через 7 дней.
+	ExpiresAt *time.Time `json:"expires_at"`
+	Email     string     `json:"email" binding:"omitempty,email"`
+	ProjectID *uint      `json:"project_id"`
+}
+
+func generateInviteCode() (string, error) {
+	b := make([]byte, inviteCodeLength)
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// projectRoleFor — роль в проекте, которую получает зарегистрированный по коду пользователь.
+func projectRoleFor(role models.Role) models.ProjectRole {
+	switch role {
+	case models.RoleManager:
+		return models.ProjectRoleManager
+	case models.RoleEngineer:
+		return models.ProjectRoleEngineer
+	case models.RoleCustomer:
+		return models.ProjectRoleCustomer
+	}
+	return models.ProjectRoleObserver
+}
+
+// findRoleCode ищет пригодный для регистрации код. Код, привязанный к почте,
+// подходит только для неё.
+func findRoleCode(db *gorm.DB, role models.Role, code, email string) (models.RoleCode, bool) {
+	var rc models.RoleCode
+	if err := db.Where("role = ? AND code = ?", role, strings.ToUpper(strings.TrimSpace(code))).First(&rc).Error; err != nil {
+		return rc, false
+	}
+	if !rc.Usable(time.Now()) {
+		return rc, false
+	}
+	if rc.Email != "" && !strings.EqualFold(rc.Email, strings.TrimSpace(email)) {
+		return rc, false
+	}
+	return rc, true
+}
+
+// redeemRoleCode списывает одно использование кода, записывает, кто его
+// использовал, и добавляет пользователя в проект, если код к нему привязан.
+func redeemRoleCode(tx *gorm.DB, rc models.RoleCode, user models.User) error {
+	// Условие в UPDATE не даёт двум параллельным регистрациям превысить лимит.
+	res := tx.Model(&models.RoleCode{}).
+		Where("id = ? AND revoked_at IS NULL AND (max_uses = 0 OR uses < max_uses)", rc.ID).
+		Update("uses", gorm.Expr("uses + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errInviteCodeUsedUp
+	}
+	if err := tx.Create(&models.RoleCodeRedemption{RoleCodeID: rc.ID, UserID: user.ID}).Error; err != nil {
+		return err
+	}
+	if rc.ProjectID != nil {
+		return ensureProjectMember(tx, *rc.ProjectID, user.ID, projectRoleFor(user.Role))
+	}
+	return nil
+}
+
+// CreateInviteHandler выпускает новый код приглашения.
+func CreateInviteHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in CreateInviteInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch in.Role {
+		case models.RoleManager, models.RoleEngineer, models.RoleCustomer:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		rc := models.RoleCode{Role: in.Role, MaxUses: 1, Email: normalizeEmail(in.Email), ProjectID: in.ProjectID}
+		if in.MaxUses != nil {
+			if *in.MaxUses < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "max_uses must not be negative"})
+				return
+			}
+			rc.MaxUses = *in.MaxUses
+		}
+		expires := time.Now().Add(defaultInviteTTL)
+		if in.ExpiresAt != nil {
+			if !in.ExpiresAt.After(time.Now()) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+				return
+			}
+			expires = *in.ExpiresAt
+		}
+		rc.ExpiresAt = &expires
+
+		if in.ProjectID != nil {
+			var project models.Project
+			if err := db.First(&project, *in.ProjectID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+				return
+			}
+		}
+
+		actorID := middleware.CurrentUserID(c)
+		rc.CreatedByID = &actorID
+
+		code, err := generateInviteCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot generate code"})
+			return
+		}
+		rc.Code = code
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&rc).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, actorID, "role_code", rc.ID, "create",
+				fmt.Sprintf("role=%s max_uses=%d expires_at=%s email=%q project=%s",
+					rc.Role, rc.MaxUses, formatValue(rc.ExpiresAt), rc.Email, formatValue(rc.ProjectID)))
+		})
+		if err != nil {
+			log.Println("Failed to create invite:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		c.JSON(http.StatusCreated, rc)
+	}
+}
+
+// ListInvitesHandler — список кодов с теми, кто по ним зарегистрировался.
+// ?active=true оставляет только коды, которыми ещё можно воспользоваться.
+func ListInvitesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := db.Model(&models.RoleCode{})
+		if c.Query("active") == "true" {
+			q = q.Where("revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR uses < max_uses)", time.Now())
+		}
+
+		var codes []models.RoleCode
+		if !paginate(c, q, inviteListSpec, &codes) {
+			return
+		}
+		c.JSON(http.StatusOK, codes)
+	}
+}
+
+// RevokeInviteHandler отзывает код. Уже зарегистрированных пользователей это не затрагивает.
+func RevokeInviteHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rc models.RoleCode
+		if err := db.First(&rc, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invite not found"})
+			return
+		}
+		if rc.RevokedAt != nil {
+			c.JSON(http.StatusOK, rc)
+			return
+		}
+
+		now := time.Now()
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&rc).Update("revoked_at", now).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "role_code", rc.ID, "revoke", "")
+		})
+		if err != nil {
+			log.Println("Failed to revoke invite:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+			return
+		}
+		rc.RevokedAt = &now
+		c.JSON(http.StatusOK, rc)
+	}
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler публикует открытые ключи, которыми другие сервисы проверяют наши токены.
+func JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+	}
+}
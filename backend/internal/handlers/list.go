@@ -0,0 +1,131 @@
+package handlers
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+const (
+    defaultPageLimit = 50
+    maxPageLimit     = 200
+)
+
+// listSpec описывает, как списочный эндпоинт разбирает параметры запроса.
+type listSpec struct {
+    // Filters: параметр запроса → колонка, фильтр по равенству.
+    Filters map[string]string
+    // Sortable: значение sort= → колонка. Сортировать можно только по индексированным колонкам.
+    Sortable map[string]string
+    // DueColumn включает фильтры due_from/due_to.
+    DueColumn   string
+    DefaultSort string
+    Preload     []string
+    // PreloadWhere: путь из Preload → условие для загружаемой связи.
+    PreloadWhere map[string]string
+}
+
+// paginate применяет к q фильтры, сортировку и пагинацию (?page=&limit=&sort=-due_date),
+// загружает страницу в dest и выставляет заголовки X-Total-Count, X-Page, X-Limit.
+// Без page/limit отдаёт первую страницу из defaultPageLimit записей; limit
+// обрезается до maxPageLimit. При ошибке сам пишет ответ и возвращает false.
+func paginate(c *gin.Context, q *gorm.DB, spec listSpec, dest interface{}) bool {
+    page, limit, err := parsePage(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return false
+    }
+
+    for param, column := range spec.Filters {
+        if v := c.Query(param); v != "" {
+            q = q.Where(column+" = ?", v)
+        }
+    }
+    if spec.DueColumn != "" {
+        for param, op := range map[string]string{"due_from": ">=", "due_to": "<="} {
+            v := c.Query(param)
+            if v == "" {
+                continue
+            }
+            t, err := parseDate(v)
+            if err != nil {
+                c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+                return false
+            }
+            q = q.Where(spec.DueColumn+" "+op+" ?", t)
+        }
+    }
+
+    order, err := parseSort(c.DefaultQuery("sort", spec.DefaultSort), spec.Sortable)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return false
+    }
+
+    var total int64
+    if err := q.Session(&gorm.Session{}).Model(dest).Count(&total).Error; err != nil {
+        log.Println("Failed to count list:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+        return false
+    }
+
+    for _, p := range spec.Preload {
+        if cond, ok := spec.PreloadWhere[p]; ok {
+            q = q.Preload(p, cond)
+        } else {
+            q = q.Preload(p)
+        }
+    }
+    q = q.Order(order).Offset((page - 1) * limit).Limit(limit)
+    if err := q.Find(dest).Error; err != nil {
+        log.Println("Failed to load list:", err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+        return false
+    }
+
+    c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+    c.Header("X-Page", strconv.Itoa(page))
+    c.Header("X-Limit", strconv.Itoa(limit))
+    return true
+}
+
+func parsePage(c *gin.Context) (int, int, error) {
+    page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+    if err != nil || page < 1 {
+        return 0, 0, fmt.Errorf("invalid page")
+    }
+    limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+    if err != nil || limit < 1 {
+        return 0, 0, fmt.Errorf("invalid limit")
+    }
+    if limit > maxPageLimit {
+        limit = maxPageLimit
+    }
+    return page, limit, nil
+}
+
+// parseSort принимает "field" или "-field" (по убыванию).
+func parseSort(sort string, sortable map[string]string) (string, error) {
+    desc := strings.HasPrefix(sort, "-")
+    column, ok := sortable[strings.TrimPrefix(sort, "-")]
+    if !ok {
+        return "", fmt.Errorf("cannot sort by %q", sort)
+    }
+    if desc {
+        return column + " DESC", nil
+    }
+    return column + " ASC", nil
+}
+
+func parseDate(v string) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, v); err == nil {
+        return t, nil
+    }
+    return time.ParseInLocation("2006-01-02", v, time.Local)
+}
@@ -1,14 +1,32 @@
 package handlers
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
+	"controlSystem/internal/middleware"
 	"controlSystem/internal/models"
+	"controlSystem/internal/utils"
 	"gorm.io/gorm"
 
 	"github.com/gin-gonic/gin"
 )
 
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+type preferencesInput struct {
+	Language             *string `json:"language"`
+	EmailNotifications   *bool   `json:"email_notifications"`
+	NotifyOnAssign       *bool   `json:"notify_on_assign"`
+	NotifyOnStatusChange *bool   `json:"notify_on_status_change"`
+	NotifyOnComment      *bool   `json:"notify_on_comment"`
+}
+
 func MeHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID, exists := c.Get("userID")
@@ -23,11 +41,171 @@ func MeHandler(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"id":        user.ID,
-			"full_name": user.FullName,
-			"email":     user.Email,
-			"role":      user.Role,
+		c.JSON(http.StatusOK, meResponse(user, loadPreferences(db, user.ID)))
+	}
+}
+
+func meResponse(user models.User, prefs models.UserPreferences) gin.H {
+	return gin.H{
+		"id":                user.ID,
+		"full_name":         user.FullName,
+		"email":             user.Email,
+		"role":              user.Role,
+		"email_verified_at": user.EmailVerifiedAt,
+		"preferences":       prefs,
+	}
+}
+
+// loadPreferences возвращает сохранённые настройки или значения по умолчанию.
+func loadPreferences(db *gorm.DB, userID uint) models.UserPreferences {
+	prefs := models.DefaultPreferences(userID)
+	if err := db.Where("user_id = ?", userID).Take(&prefs).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("Failed to load preferences:", err)
+	}
+	return prefs
+}
+
+// UpdateMeHandler — пользователь меняет своё ФИО. Почту и роль меняет только администратор.
+func UpdateMeHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			FullName string `json:"full_name" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		name := strings.TrimSpace(input.FullName)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "full_name must not be empty"})
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+
+		changes := newFieldChanges()
+		changes.set("full_name", "ФИО", user.FullName, name)
+		if !changes.empty() {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Updates(changes.updates).Error; err != nil {
+					return err
+				}
+				return writeAudit(tx, user.ID, "user", user.ID, "Изменение профиля", changes.String())
+			})
+			if err != nil {
+				log.Println("Failed to update profile:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update profile"})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, meResponse(user, loadPreferences(db, user.ID)))
+	}
+}
+
+// ChangePasswordHandler меняет пароль после проверки текущего. Все сессии,
+// включая текущую, завершаются; в ответе — токены новой сессии, чтобы
+// пользователю не пришлось входить заново на этом устройстве.
+func ChangePasswordHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input ChangePasswordInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+
+		keys := []throttleKey{{Key: "password:account:" + normalizeEmail(user.Email), Policy: loginAccountPolicy}}
+		if wait := throttleWait(db, keys); wait > 0 {
+			tooManyAttempts(c, wait)
+			return
+		}
+		if err := utils.CheckPasswordHash(user.Password, input.CurrentPassword); err != nil {
+			throttleFail(db, keys)
+			recordAuthFailure(db, c, "password", user.Email, &user.ID, "invalid_credentials")
+			c.JSON(http.StatusForbidden, gin.H{"error": "неверный текущий пароль"})
+			return
+		}
+		throttleReset(db, keys[0].Key)
+
+		hashed, err := utils.HashPassword(input.NewPassword)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't hash password"})
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Update("password", hashed).Error; err != nil {
+				return err
+			}
+			if err := revokeUserSessions(tx, user.ID); err != nil {
+				return err
+			}
+			return writeAudit(tx, user.ID, "user", user.ID, "Смена пароля", "")
 		})
+		if err != nil {
+			log.Println("Failed to change password:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot change password"})
+			return
+		}
+
+		// новая сессия должна получить уже увеличенное поколение сессий
+		if err := db.First(&user, user.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot change password"})
+			return
+		}
+		startSession(c, db, user)
+	}
+}
+
+func GetPreferencesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, loadPreferences(db, middleware.CurrentUserID(c)))
+	}
+}
+
+// UpdatePreferencesHandler сохраняет переданные настройки; остальные не меняются.
+func UpdatePreferencesHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input preferencesInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		prefs := loadPreferences(db, middleware.CurrentUserID(c))
+		if input.Language != nil {
+			if !models.SupportedLanguages[*input.Language] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported language"})
+				return
+			}
+			prefs.Language = *input.Language
+		}
+		for dst, src := range map[*bool]*bool{
+			&prefs.EmailNotifications:   input.EmailNotifications,
+			&prefs.NotifyOnAssign:       input.NotifyOnAssign,
+			&prefs.NotifyOnStatusChange: input.NotifyOnStatusChange,
+			&prefs.NotifyOnComment:      input.NotifyOnComment,
+		} {
+			if src != nil {
+				*dst = *src
+			}
+		}
+
+		// Save делает upsert по первичному ключу user_id.
+		if err := db.Save(&prefs).Error; err != nil {
+			log.Println("Failed to save preferences:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot save preferences"})
+			return
+		}
+		c.JSON(http.StatusOK, prefs)
 	}
 }
@@ -1,18 +1,34 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"controlSystem/internal/middleware"
 	"controlSystem/internal/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+var projectListSpec = listSpec{
+	Filters: map[string]string{
+		"manager_id":  "manager_id",
+		"customer_id": "customer_id",
+		"active":      "active",
+	},
+	Sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"name":       "name",
+	},
+	DefaultSort: "-created_at",
+	Preload:     []string{"Manager", "Customer", "Tasks"},
+}
+
 func ListProjectsHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var projects []models.Project
-		if err := db.Preload("Manager").Preload("Customer").Preload("Tasks").Find(&projects).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось получить проекты"})
+		if !paginate(c, db.Scopes(visibleProjects(c, "id")), projectListSpec, &projects) {
 			return
 		}
 		c.JSON(http.StatusOK, projects)
@@ -23,7 +39,7 @@ func GetProjectByID(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
         id := c.Param("id")
         var project models.Project
-        if err := db.Preload("Manager").Preload("Customer").First(&project, id).Error; err != nil {
+        if err := db.Scopes(visibleProjects(c, "id")).Preload("Manager").Preload("Customer").First(&project, id).Error; err != nil {
             c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
             return
         }
@@ -52,10 +68,204 @@ func CreateProjectHandler(db *gorm.DB) gin.HandlerFunc {
 			CustomerID:  input.CustomerID,
 			Active:      input.Active,
 		}
-		if err := db.Create(&project).Error; err != nil {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := checkTargetUser(tx, project.ManagerID, models.RoleManager, errInvalidManager); err != nil {
+				return err
+			}
+			if err := checkTargetUser(tx, project.CustomerID, models.RoleCustomer, errInvalidCustomer); err != nil {
+				return err
+			}
+			if err := tx.Create(&project).Error; err != nil {
+				return err
+			}
+			if err := ensureProjectMember(tx, project.ID, project.ManagerID, models.ProjectRoleManager); err != nil {
+				return err
+			}
+			if err := ensureProjectMember(tx, project.ID, project.CustomerID, models.ProjectRoleCustomer); err != nil {
+				return err
+			}
+			// создатель должен видеть проект, даже если назначил менеджером другого
+			return ensureProjectMember(tx, project.ID, middleware.CurrentUserID(c), models.ProjectRoleManager)
+		})
+		if errors.Is(err, errInvalidManager) || errors.Is(err, errInvalidCustomer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось создать проект"})
 			return
 		}
 		c.JSON(http.StatusOK, project)
 	}
 }
+
+func UpdateProjectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+			ManagerID   *uint   `json:"manager_id"`
+			CustomerID  *uint   `json:"customer_id"`
+			Active      *bool   `json:"active"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var project models.Project
+		if err := db.Scopes(visibleProjects(c, "id")).First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+
+		changes := newFieldChanges()
+		if input.Name != nil {
+			changes.set("name", "Название", project.Name, *input.Name)
+		}
+		if input.Description != nil {
+			changes.set("description", "Описание", project.Description, *input.Description)
+		}
+		if input.ManagerID != nil {
+			changes.set("manager_id", "Менеджер", project.ManagerID, *input.ManagerID)
+		}
+		if input.CustomerID != nil {
+			changes.set("customer_id", "Заказчик", project.CustomerID, *input.CustomerID)
+		}
+		if input.Active != nil {
+			changes.set("active", "Активен", project.Active, *input.Active)
+		}
+		if changes.empty() {
+			c.JSON(http.StatusOK, project)
+			return
+		}
+
+		actorID := middleware.CurrentUserID(c)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if input.ManagerID != nil {
+				if err := checkTargetUser(tx, *input.ManagerID, models.RoleManager, errInvalidManager); err != nil {
+					return err
+				}
+			}
+			if input.CustomerID != nil {
+				if err := checkTargetUser(tx, *input.CustomerID, models.RoleCustomer, errInvalidCustomer); err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&project).Updates(changes.updates).Error; err != nil {
+				return err
+			}
+			if input.ManagerID != nil {
+				if err := ensureProjectMember(tx, project.ID, *input.ManagerID, models.ProjectRoleManager); err != nil {
+					return err
+				}
+			}
+			if input.CustomerID != nil {
+				if err := ensureProjectMember(tx, project.ID, *input.CustomerID, models.ProjectRoleCustomer); err != nil {
+					return err
+				}
+			}
+			return writeAudit(tx, actorID, "project", project.ID, "Изменение проекта", changes.String())
+		})
+		if errors.Is(err, errInvalidManager) || errors.Is(err, errInvalidCustomer) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось обновить проект"})
+			return
+		}
+		c.JSON(http.StatusOK, project)
+	}
+}
+
+func ArchiveProjectHandler(db *gorm.DB) gin.HandlerFunc {
+	return setProjectActive(db, false)
+}
+
+func UnarchiveProjectHandler(db *gorm.DB) gin.HandlerFunc {
+	return setProjectActive(db, true)
+}
+
+func setProjectActive(db *gorm.DB, active bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var project models.Project
+		if err := db.Scopes(visibleProjects(c, "id")).First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+
+		action := "Архивирование проекта"
+		if active {
+			action = "Восстановление проекта из архива"
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&project).Update("active", active).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "project", project.ID, action, "")
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось обновить проект"})
+			return
+		}
+		c.JSON(http.StatusOK, project)
+	}
+}
+
+// ProjectHistoryHandler — журнал изменений проекта, включая удалённые проекты.
+func ProjectHistoryHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var project models.Project
+		if err := db.Unscoped().Scopes(visibleProjects(c, "id")).First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+		respondAudit(c, db, "project", project.ID)
+	}
+}
+
+func DeleteProjectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var project models.Project
+		if err := db.Scopes(visibleProjects(c, "id")).First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&project).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "project", project.ID, "Удаление проекта", project.Name)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось удалить проект"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+	}
+}
+
+func RestoreProjectHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var project models.Project
+		if err := db.Unscoped().Scopes(visibleProjects(c, "id")).Where("deleted_at IS NOT NULL").First(&project, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "deleted project not found"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Unscoped().Model(&project).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "project", project.ID, "Восстановление проекта", project.Name)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось восстановить проект"})
+			return
+		}
+		project.DeletedAt = gorm.DeletedAt{}
+		c.JSON(http.StatusOK, project)
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "controlSystem/internal/models"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+func ListProjectMembersHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        projectID, err := strconv.Atoi(c.Param("id"))
+        if err != nil || !projectVisible(c, db, uint(projectID)) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+
+        var members []models.ProjectMember
+        if err := db.Preload("User").Where("project_id = ?", projectID).Order("created_at asc").Find(&members).Error; err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
+            return
+        }
+        c.JSON(http.StatusOK, members)
+    }
+}
+
+func AddProjectMemberHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        projectID, err := strconv.Atoi(c.Param("id"))
+        if err != nil || !projectVisible(c, db, uint(projectID)) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+
+        var req struct {
+            UserID uint               `json:"user_id" binding:"required"`
+            Role   models.ProjectRole `json:"role" binding:"required"`
+        }
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if !req.Role.Valid() {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid project role"})
+            return
+        }
+
+        var user models.User
+        if err := db.First(&user, req.UserID).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+            return
+        }
+
+        member := models.ProjectMember{ProjectID: uint(projectID), UserID: req.UserID}
+        err = db.Where(&member).Take(&member).Error
+        switch {
+        case err == nil:
+            member.Role = req.Role
+            err = db.Save(&member).Error
+        case errors.Is(err, gorm.ErrRecordNotFound):
+            member.Role = req.Role
+            err = db.Create(&member).Error
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось добавить участника"})
+            return
+        }
+
+        member.User = user.Public()
+        c.JSON(http.StatusOK, member)
+    }
+}
+
+func RemoveProjectMemberHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        projectID, err := strconv.Atoi(c.Param("id"))
+        if err != nil || !projectVisible(c, db, uint(projectID)) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+
+        userID, err := strconv.Atoi(c.Param("userId"))
+        if err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "участник не найден"})
+            return
+        }
+        var project models.Project
+        if err := db.First(&project, projectID).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+        // менеджер и заказчик проекта всегда остаются участниками
+        if uint(userID) == project.ManagerID || uint(userID) == project.CustomerID {
+            c.JSON(http.StatusConflict, gin.H{"error": "нельзя удалить менеджера или заказчика проекта: сначала назначьте другого"})
+            return
+        }
+
+        res := db.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&models.ProjectMember{})
+        if res.Error != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось удалить участника"})
+            return
+        }
+        if res.RowsAffected == 0 {
+            c.JSON(http.StatusNotFound, gin.H{"error": "участник не найден"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "removed"})
+    }
+}
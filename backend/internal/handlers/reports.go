@@ -37,6 +37,11 @@ func GetTaskReports(db *gorm.DB) gin.HandlerFunc {
             return
         }
 
+        if !projectVisible(c, db, uint(projectID)) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "project not found"})
+            return
+        }
+
         var tasks []models.Task
         if err := db.Preload("RelatedDefect.Project").
             Preload("RelatedDefect.History").
@@ -64,7 +69,7 @@ func GetTaskReports(db *gorm.DB) gin.HandlerFunc {
                 ProjectName:  safeProjectName(t),
                 DefectName:   safeDefectTitle(t),
                 TaskName:     t.Name,
-                Status:       t.Status,
+                Status:       string(t.Status),
                 AssigneeName: safeAssigneeName(t),
                 DueDate:      t.DueDate,
                 HistoryCount: historyCount,
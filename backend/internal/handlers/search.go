@@ -0,0 +1,122 @@
+package handlers
+
+import (
+    "html"
+    "log"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+const (
+    // Выражения должны совпадать с GIN-индексами из migrations.createSearchIndexes,
+    // иначе Postgres не сможет их использовать.
+    defectSearchDocument  = "to_tsvector('russian', coalesce(defects.title, '') || ' ' || coalesce(defects.description, ''))"
+    taskSearchDocument    = "to_tsvector('russian', coalesce(tasks.name, '') || ' ' || coalesce(tasks.description, ''))"
+    commentSearchDocument = "to_tsvector('russian', coalesce(defect_histories.action_text, ''))"
+
+    // ts_headline работает с сырым текстом, поэтому совпадения помечаются символами
+    // из области частного использования, а HTML собирается в highlightSnippet.
+    headlineStart         = "\uE000"
+    headlineStop          = "\uE001"
+    searchHeadlineOptions = "StartSel=" + headlineStart + ", StopSel=" + headlineStop + ", MaxFragments=2, MaxWords=20, MinWords=5"
+    defaultSearchLimit    = 20
+    maxSearchLimit        = 50
+)
+
+type SearchHit struct {
+    ID        uint    `json:"id"`
+    DefectID  *uint   `json:"defect_id,omitempty"`
+    ProjectID uint    `json:"project_id"`
+    Title     string  `json:"title"`
+    Status    string  `json:"status,omitempty"`
+    Snippet   string  `json:"snippet"`
+    Rank      float64 `json:"rank"`
+}
+
+type SearchResult struct {
+    Defects  []SearchHit `json:"defects"`
+    Tasks    []SearchHit `json:"tasks"`
+    Comments []SearchHit `json:"comments"`
+}
+
+// SearchHandler — полнотекстовый поиск по дефектам, задачам и комментариям
+// с русской морфологией. Результаты сгруппированы по типу и отсортированы по рангу.
+func SearchHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        q := strings.TrimSpace(c.Query("q"))
+        if q == "" {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+            return
+        }
+        limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultSearchLimit)))
+        if err != nil || limit < 1 {
+            c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+            return
+        }
+        if limit > maxSearchLimit {
+            limit = maxSearchLimit
+        }
+
+        result := SearchResult{Defects: []SearchHit{}, Tasks: []SearchHit{}, Comments: []SearchHit{}}
+
+        err = db.Table("defects").
+            Select("defects.id, defects.project_id, defects.title, defects.status, "+
+                "ts_rank("+defectSearchDocument+", query) AS rank, "+
+                "ts_headline('russian', coalesce(defects.description, defects.title), query, ?) AS snippet", searchHeadlineOptions).
+            Joins("CROSS JOIN websearch_to_tsquery('russian', ?) query", q).
+            Where("defects.deleted_at IS NULL").
+            Where(defectSearchDocument + " @@ query").
+            Scopes(visibleProjects(c, "defects.project_id")).
+            Order("rank DESC").Limit(limit).
+            Scan(&result.Defects).Error
+        if err == nil {
+            err = db.Table("tasks").
+                Select("tasks.id, tasks.related_defect_id AS defect_id, tasks.project_id, tasks.name AS title, tasks.status, "+
+                    "ts_rank("+taskSearchDocument+", query) AS rank, "+
+                    "ts_headline('russian', coalesce(tasks.description, tasks.name), query, ?) AS snippet", searchHeadlineOptions).
+                Joins("CROSS JOIN websearch_to_tsquery('russian', ?) query", q).
+                Where("tasks.deleted_at IS NULL").
+                Where(taskSearchDocument + " @@ query").
+                Scopes(visibleProjects(c, "tasks.project_id")).
+                Order("rank DESC").Limit(limit).
+                Scan(&result.Tasks).Error
+        }
+        if err == nil {
+            err = db.Table("defect_histories").
+                Select("defect_histories.id, defect_histories.defect_id, defects.project_id, defects.title, "+
+                    "ts_rank("+commentSearchDocument+", query) AS rank, "+
+                    "ts_headline('russian', defect_histories.action_text, query, ?) AS snippet", searchHeadlineOptions).
+                Joins("JOIN defects ON defects.id = defect_histories.defect_id AND defects.deleted_at IS NULL").
+                Joins("CROSS JOIN websearch_to_tsquery('russian', ?) query", q).
+                Where("defect_histories.action_type = ?", "Комментарий").
+                Where(commentSearchDocument + " @@ query").
+                Scopes(visibleProjects(c, "defects.project_id")).
+                Order("rank DESC").Limit(limit).
+                Scan(&result.Comments).Error
+        }
+        if err != nil {
+            log.Println("Search failed:", err)
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+            return
+        }
+
+        for _, hits := range [][]SearchHit{result.Defects, result.Tasks, result.Comments} {
+            for i := range hits {
+                hits[i].Snippet = highlightSnippet(hits[i].Snippet)
+            }
+        }
+        c.JSON(http.StatusOK, result)
+    }
+}
+
+// highlightSnippet экранирует текст фрагмента и только затем превращает
+// маркеры ts_headline в <mark>, так что HTML из дефектов и комментариев
+// выводится как текст.
+func highlightSnippet(snippet string) string {
+    escaped := html.EscapeString(snippet)
+    return strings.NewReplacer(headlineStart, "<mark>", headlineStop, "</mark>").Replace(escaped)
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+var errRefreshTokenInvalid = errors.New("invalid refresh token")
+
+// issueRefreshToken создаёт refresh-токен в сессии sessionID и возвращает его
+// открытое значение — клиент получает его один раз, в БД остаётся хэш.
+func issueRefreshToken(tx *gorm.DB, c *gin.Context, userID uint, sessionID string) (string, models.RefreshToken, error) {
+	raw, err := utils.RandomToken(32)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	rt := models.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: utils.HashToken(raw),
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+	if err := tx.Create(&rt).Error; err != nil {
+		return "", rt, err
+	}
+	return raw, rt, nil
+}
+
+// sessionResponse — пара токенов, которую получает клиент при входе и обновлении.
+func sessionResponse(user models.User, sessionID, refresh string) (gin.H, error) {
+	access, _, err := utils.GenerateToken(user.ID, string(user.Role), sessionID, user.SessionGeneration)
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":         access,
+		"refresh_token": refresh,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+		"user": gin.H{
+			"id":        user.ID,
+			"full_name": user.FullName,
+			"email":     user.Email,
+			"role":      user.Role,
+		},
+	}, nil
+}
+
+// startSession открывает новую сессию пользователя и пишет ответ с токенами.
+func startSession(c *gin.Context, db *gorm.DB, user models.User) {
+	sessionID, err := utils.RandomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create token"})
+		return
+	}
+	refresh, _, err := issueRefreshToken(db, c, user.ID, sessionID)
+	if err != nil {
+		log.Println("Failed to store refresh token:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create token"})
+		return
+	}
+	resp, err := sessionResponse(user, sessionID, refresh)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create token"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// revokeSession отзывает все ещё действующие refresh-токены сессии.
+func revokeSession(tx *gorm.DB, sessionID string) error {
+	return tx.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeAccessToken заносит jti текущего access-токена в список отозванных
+// и заодно чистит записи, срок действия которых уже истёк.
+func revokeAccessToken(tx *gorm.DB, c *gin.Context) error {
+	claims := middleware.CurrentClaims(c)
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Save(&models.RevokedToken{JTI: claims.ID, ExpiresAt: claims.ExpiresAt.Time}).Error
+}
+
+// RefreshHandler обменивает refresh-токен на новую пару токенов. Каждый
+// refresh-токен одноразовый: повторное использование уже заменённого токена
+// отзывает всю сессию.
+func RefreshHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in RefreshInput
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var (
+			user    models.User
+			session string
+			refresh string
+		)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var rt models.RefreshToken
+			if err := tx.Where("token_hash = ?", utils.HashToken(in.RefreshToken)).First(&rt).Error; err != nil {
+				return errRefreshTokenInvalid
+			}
+			if rt.RevokedAt != nil {
+				if rt.ReplacedByID != nil {
+					log.Printf("Refresh token reuse detected for user %d, revoking session", rt.UserID)
+					if err := revokeSession(tx, rt.SessionID); err != nil {
+						return err
+					}
+					// Отзыв должен сохраниться, поэтому ошибку отдаём уже после коммита.
+					return nil
+				}
+				return errRefreshTokenInvalid
+			}
+			if time.Now().After(rt.ExpiresAt) {
+				return errRefreshTokenInvalid
+			}
+			if err := tx.First(&user, rt.UserID).Error; err != nil || user.DeactivatedAt != nil {
+				return errRefreshTokenInvalid
+			}
+
+			raw, next, err := issueRefreshToken(tx, c, rt.UserID, rt.SessionID)
+			if err != nil {
+				return err
+			}
+			// Условие на revoked_at не даёт обменять один токен дважды параллельно.
+			res := tx.Model(&rt).Where("revoked_at IS NULL").
+				Updates(map[string]interface{}{"revoked_at": time.Now(), "replaced_by_id": next.ID})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errRefreshTokenInvalid
+			}
+			session, refresh = rt.SessionID, raw
+			return nil
+		})
+		if errors.Is(err, errRefreshTokenInvalid) || (err == nil && refresh == "") {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errRefreshTokenInvalid.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Failed to refresh token:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't refresh token"})
+			return
+		}
+
+		resp, err := sessionResponse(user, session, refresh)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "couldn't create token"})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// LogoutHandler завершает текущую сессию: отзывает её refresh-токены и сам access-токен.
+func LogoutHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if claims := middleware.CurrentClaims(c); claims != nil && claims.SessionID != "" {
+				if err := revokeSession(tx, claims.SessionID); err != nil {
+					return err
+				}
+			}
+			return revokeAccessToken(tx, c)
+		})
+		if err != nil {
+			log.Println("Failed to logout:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot logout"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+	}
+}
+
+// LogoutAllHandler завершает все сессии пользователя на всех устройствах.
+// Access-токены, выпущенные до этого момента, отклоняет AuthMiddleware.
+func LogoutAllHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := middleware.CurrentUserID(c)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := revokeUserSessions(tx, userID); err != nil {
+				return err
+			}
+			return revokeAccessToken(tx, c)
+		})
+		if err != nil {
+			log.Println("Failed to logout all sessions:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot logout"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "all sessions logged out"})
+	}
+}
+
+// revokeUserSessions отзывает все refresh-токены пользователя и начинает новое
+// поколение сессий: выпущенные ранее access-токены отклоняет AuthMiddleware.
+func revokeUserSessions(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.User{}).Where("id = ?", userID).
+		Update("session_generation", gorm.Expr("session_generation + 1")).Error
+}
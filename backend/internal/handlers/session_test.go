@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	testEmail    = "engineer@example.com"
+	testPassword = "Secret123!"
+)
+
+type sessionEnv struct {
+	db     *gorm.DB
+	router *gin.Engine
+}
+
+// newSessionEnv — вход по паролю, обновление токенов и маршруты под AuthMiddleware.
+func newSessionEnv(t *testing.T) *sessionEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := utils.InitSigningKeys(utils.KeyConfig{DevMode: true}); err != nil {
+		t.Fatalf("signing keys: %v", err)
+	}
+
+	env := &sessionEnv{db: newTestDB(t,
+		&models.User{}, &models.RefreshToken{}, &models.RevokedToken{},
+		&models.AuthThrottle{}, &models.AuthFailure{}, &models.AuditLog{},
+		&models.UserPreferences{},
+	)}
+	hashed, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user := models.User{FullName: "Инженер", Email: testEmail, Password: hashed, Role: models.RoleEngineer}
+	if err := env.db.Create(&user).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	env.router = gin.New()
+	api := env.router.Group("/api")
+	api.POST("/register", RegisterHandler(env.db, AccountConfig{}))
+	api.POST("/login", LoginHandler(env.db, AccountConfig{}))
+	api.POST("/refresh", RefreshHandler(env.db))
+	auth := api.Group("/", middleware.AuthMiddleware(env.db))
+	auth.GET("/me", MeHandler(env.db))
+	auth.PUT("/me/password", ChangePasswordHandler(env.db))
+	auth.POST("/logout-all", LogoutAllHandler(env.db))
+	return env
+}
+
+func (env *sessionEnv) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload string
+	if body != nil {
+		b, _ := json.Marshal(body)
+		payload = string(b)
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+type sessionTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// tokens разбирает ответ с парой токенов, ожидая код 200.
+func tokens(t *testing.T, w *httptest.ResponseRecorder) sessionTokens {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var s sessionTokens
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil || s.Token == "" {
+		t.Fatalf("no tokens in response %s", w.Body)
+	}
+	return s
+}
+
+func (env *sessionEnv) login(t *testing.T) sessionTokens {
+	t.Helper()
+	return env.loginAs(t, testEmail)
+}
+
+func (env *sessionEnv) loginAs(t *testing.T, email string) sessionTokens {
+	t.Helper()
+	return tokens(t, env.do(http.MethodPost, "/api/login", "", map[string]string{
+		"email": email, "password": testPassword,
+	}))
+}
+
+func (env *sessionEnv) assertMe(t *testing.T, token string, want int) {
+	t.Helper()
+	if w := env.do(http.MethodGet, "/api/me", token, nil); w.Code != want {
+		t.Fatalf("GET /api/me = %d, want %d: %s", w.Code, want, w.Body)
+	}
+}
+
+func TestLoginRightAfterLogoutAllIsAccepted(t *testing.T) {
+	env := newSessionEnv(t)
+	old := env.login(t)
+
+	if w := env.do(http.MethodPost, "/api/logout-all", old.Token, nil); w.Code != http.StatusOK {
+		t.Fatalf("logout-all = %d: %s", w.Code, w.Body)
+	}
+	// новый вход почти наверняка в ту же секунду, что и выход
+	fresh := env.login(t)
+
+	env.assertMe(t, fresh.Token, http.StatusOK)
+	env.assertMe(t, old.Token, http.StatusUnauthorized)
+
+	refreshed := tokens(t, env.do(http.MethodPost, "/api/refresh", "", RefreshInput{RefreshToken: fresh.RefreshToken}))
+	env.assertMe(t, refreshed.Token, http.StatusOK)
+	if w := env.do(http.MethodPost, "/api/refresh", "", RefreshInput{RefreshToken: old.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh with revoked token = %d, want 401", w.Code)
+	}
+}
+
+func TestLogoutAllRevokesOtherSessions(t *testing.T) {
+	env := newSessionEnv(t)
+	phone := env.login(t)
+	laptop := env.login(t)
+
+	if w := env.do(http.MethodPost, "/api/logout-all", laptop.Token, nil); w.Code != http.StatusOK {
+		t.Fatalf("logout-all = %d: %s", w.Code, w.Body)
+	}
+	env.assertMe(t, phone.Token, http.StatusUnauthorized)
+	env.assertMe(t, laptop.Token, http.StatusUnauthorized)
+}
+
+func TestChangePasswordReturnsWorkingSession(t *testing.T) {
+	env := newSessionEnv(t)
+	old := env.login(t)
+
+	fresh := tokens(t, env.do(http.MethodPut, "/api/me/password", old.Token, ChangePasswordInput{
+		CurrentPassword: testPassword, NewPassword: "Changed456!",
+	}))
+
+	env.assertMe(t, fresh.Token, http.StatusOK)
+	env.assertMe(t, old.Token, http.StatusUnauthorized)
+}
+
+func TestEmailIsCaseInsensitive(t *testing.T) {
+	env := newSessionEnv(t)
+
+	env.assertMe(t, env.loginAs(t, "Engineer@Example.COM").Token, http.StatusOK)
+
+	w := env.do(http.MethodPost, "/api/register", "", map[string]string{
+		"full_name": "Двойник", "email": "ENGINEER@example.com", "password": testPassword, "role": "customer",
+	})
+	if w.Code != http.StatusConflict {
+		t.Fatalf("register with same email in other case = %d, want 409: %s", w.Code, w.Body)
+	}
+}
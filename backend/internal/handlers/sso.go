@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"controlSystem/internal/models"
+	"controlSystem/internal/sso"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+	ssoExchangeTTL  = time.Minute
+)
+
+var (
+	errSSONoRole          = errors.New("no role mapped for identity provider groups")
+	errSSONoEmail         = errors.New("identity provider did not return an email")
+	errSSOEmailUnverified = errors.New("email is not verified by identity provider")
+	errSSOAccountInactive = errors.New("account deactivated")
+)
+
+// OIDCConfigHandler сообщает фронтенду, показывать ли кнопку входа через SSO.
+func OIDCConfigHandler(p *sso.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"enabled": p != nil})
+	}
+}
+
+// OIDCLoginHandler начинает вход через IdP: сохраняет state, nonce и PKCE
+// verifier и перенаправляет браузер на страницу входа IdP.
+// ?redirect= — путь во фронтенде, куда вернуть пользователя после входа.
+func OIDCLoginHandler(db *gorm.DB, p *sso.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "sso is not configured"})
+			return
+		}
+
+		state, err := utils.RandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start sso login"})
+			return
+		}
+		nonce, err := utils.RandomToken(32)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start sso login"})
+			return
+		}
+		st := models.OIDCLoginState{
+			StateHash:  utils.HashToken(state),
+			Nonce:      nonce,
+			Verifier:   oauth2.GenerateVerifier(),
+			RedirectTo: safeRedirectPath(c.Query("redirect")),
+			ExpiresAt:  time.Now().Add(oidcStateTTL),
+		}
+
+		authURL, err := p.AuthURL(c.Request.Context(), state, st.Nonce, st.Verifier)
+		if err != nil {
+			log.Println("Failed to build sso url:", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "identity provider is unavailable"})
+			return
+		}
+
+		if err := db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{}).Error; err != nil {
+			log.Println("Failed to purge sso states:", err)
+		}
+		if err := db.Create(&st).Error; err != nil {
+			log.Println("Failed to store sso state:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot start sso login"})
+			return
+		}
+
+		// state дублируется в cookie: callback примет только тот браузер, который начал вход.
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(oidcStateCookie, state, int(oidcStateTTL.Seconds()), "/api/auth/oidc", "", requestIsHTTPS(c), true)
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// OIDCCallbackHandler принимает ответ IdP, находит или создаёт пользователя
+// и перенаправляет на фронтенд с одноразовым кодом для OIDCExchangeHandler.
+// Токены в адресную строку не попадают.
+func OIDCCallbackHandler(db *gorm.DB, p *sso.Provider, cfg AccountConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fail := func(reason string) {
+			c.Redirect(http.StatusFound, strings.TrimRight(cfg.FrontendURL, "/")+"/login?sso_error="+url.QueryEscape(reason))
+		}
+		if p == nil {
+			fail("not_configured")
+			return
+		}
+		if c.Query("error") != "" {
+			fail("denied")
+			return
+		}
+
+		state := c.Query("state")
+		cookie, _ := c.Cookie(oidcStateCookie)
+		c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", requestIsHTTPS(c), true)
+		if state == "" || cookie != state {
+			fail("invalid_state")
+			return
+		}
+
+		var st models.OIDCLoginState
+		if err := db.Where("state_hash = ?", utils.HashToken(state)).Take(&st).Error; err != nil {
+			fail("invalid_state")
+			return
+		}
+		// Удаление с проверкой RowsAffected делает state одноразовым.
+		res := db.Where("state_hash = ?", st.StateHash).Delete(&models.OIDCLoginState{})
+		if res.Error != nil || res.RowsAffected == 0 || time.Now().After(st.ExpiresAt) {
+			fail("invalid_state")
+			return
+		}
+
+		identity, err := p.Exchange(c.Request.Context(), c.Query("code"), st.Verifier, st.Nonce)
+		if err != nil {
+			log.Println("SSO exchange failed:", err)
+			fail("exchange_failed")
+			return
+		}
+
+		user, err := resolveSSOUser(db, p, identity)
+		switch {
+		case errors.Is(err, errSSONoRole):
+			fail("no_role")
+			return
+		case errors.Is(err, errSSONoEmail), errors.Is(err, errSSOEmailUnverified):
+			fail("email_unverified")
+			return
+		case errors.Is(err, errSSOAccountInactive):
+			fail("account_deactivated")
+			return
+		case err != nil:
+			log.Println("SSO user resolution failed:", err)
+			fail("server_error")
+			return
+		}
+
+		code, err := issueAccountToken(db, user.ID, models.PurposeSSOExchange, ssoExchangeTTL)
+		if err != nil {
+			log.Println("Failed to issue sso exchange code:", err)
+			fail("server_error")
+			return
+		}
+		target := strings.TrimRight(cfg.FrontendURL, "/") + "/sso/callback?code=" + url.QueryEscape(code) +
+			"&redirect=" + url.QueryEscape(st.RedirectTo)
+		c.Redirect(http.StatusFound, target)
+	}
+}
+
+// OIDCExchangeHandler обменивает одноразовый код из OIDCCallbackHandler на
+// обычную пару токенов.
+func OIDCExchangeHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var in struct {
+			Code string `json:"code" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&in); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var user models.User
+		err := db.Transaction(func(tx *gorm.DB) error {
+			t, err := consumeAccountToken(tx, in.Code, models.PurposeSSOExchange)
+			if err != nil {
+				return err
+			}
+			return tx.First(&user, t.UserID).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errAccountTokenInvalid.Error()})
+			return
+		}
+		if user.DeactivatedAt != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account deactivated", "code": "account_deactivated"})
+			return
+		}
+		startSession(c, db, user)
+	}
+}
+
+// resolveSSOUser находит пользователя по привязке к IdP, затем по почте
+// (только если IdP её подтвердил), иначе создаёт нового с ролью из групп IdP.
+func resolveSSOUser(db *gorm.DB, p *sso.Provider, id sso.Identity) (models.User, error) {
+	var user models.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var link models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", id.Issuer, id.Subject).Take(&link).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, link.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if id.Email == "" {
+				return errSSONoEmail
+			}
+			if !id.EmailVerified {
+				return errSSOEmailUnverified
+			}
+			err := tx.Where("lower(email) = ?", normalizeEmail(id.Email)).Take(&user).Error
+			switch {
+			case err == nil:
+				if err := writeAudit(tx, user.ID, "user", user.ID, "Привязка SSO", id.Issuer); err != nil {
+					return err
+				}
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if user, err = provisionSSOUser(tx, p, id); err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+			if err := tx.Create(&models.UserIdentity{UserID: user.ID, Issuer: id.Issuer, Subject: id.Subject, Email: id.Email}).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		if user.DeactivatedAt != nil {
+			return errSSOAccountInactive
+		}
+		if p.SyncRoles() {
+			return syncSSORole(tx, p, &user, id.Groups)
+		}
+		return nil
+	})
+	return user, err
+}
+
+func provisionSSOUser(tx *gorm.DB, p *sso.Provider, id sso.Identity) (models.User, error) {
+	role, ok := p.RoleFor(id.Groups)
+	if !ok {
+		return models.User{}, errSSONoRole
+	}
+	// Пароль случайный: такой пользователь входит через IdP, а локальный
+	// пароль при желании задаёт через сброс пароля.
+	random, err := utils.RandomToken(32)
+	if err != nil {
+		return models.User{}, err
+	}
+	hashed, err := utils.HashPassword(random)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	name := strings.TrimSpace(id.Name)
+	if name == "" {
+		name = id.Email
+	}
+	now := time.Now()
+	user := models.User{FullName: name, Email: normalizeEmail(id.Email), Password: hashed, Role: role, EmailVerifiedAt: &now}
+	if err := tx.Create(&user).Error; err != nil {
+		return user, err
+	}
+	return user, writeAudit(tx, user.ID, "user", user.ID, "Регистрация через SSO",
+		fmt.Sprintf("Роль: %s; группы: %s", role, strings.Join(id.Groups, ", ")))
+}
+
+// syncSSORole приводит роль к сопоставленной группам IdP. Если группы не
+// дают роли, текущая роль сохраняется.
+func syncSSORole(tx *gorm.DB, p *sso.Provider, user *models.User, groups []string) error {
+	role, ok := p.RoleFor(groups)
+	if !ok || role == user.Role {
+		return nil
+	}
+	old := user.Role
+	if err := tx.Model(user).Update("role", role).Error; err != nil {
+		return err
+	}
+	return writeAudit(tx, user.ID, "user", user.ID, "Изменение роли", fmt.Sprintf("Роль: %s → %s (группы IdP)", old, role))
+}
+
+// safeRedirectPath пропускает только относительные пути внутри фронтенда.
+func safeRedirectPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.Contains(p, "\\") {
+		return "/"
+	}
+	return p
+}
+
+func requestIsHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"controlSystem/internal/models"
+	"controlSystem/internal/sso"
+	"controlSystem/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	testClientID    = "control-system"
+	testFrontendURL = "http://frontend.test"
+	testIdPKeyID    = "test-key"
+)
+
+// mockIdP — минимальный OIDC-провайдер: discovery, JWKS и token endpoint
+// с проверкой PKCE. Коды выдаются тестом через authorize.
+type mockIdP struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate idp key: %v", err)
+	}
+	idp := &mockIdP{t: t, key: key, grants: map[string]mockGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"issuer":                                idp.srv.URL,
+			"authorization_endpoint":                idp.srv.URL + "/authorize",
+			"token_endpoint":                        idp.srv.URL + "/token",
+			"jwks_uri":                              idp.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		pub := idp.key.PublicKey
+		writeJSON(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testIdPKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// authorize имитирует вход пользователя на стороне IdP и возвращает code,
+// по которому token endpoint выдаст ID-токен с claims.
+func (idp *mockIdP) authorize(challenge string, claims jwt.MapClaims) string {
+	code, err := utils.RandomToken(16)
+	if err != nil {
+		idp.t.Fatalf("idp code: %v", err)
+	}
+	idp.mu.Lock()
+	idp.grants[code] = mockGrant{challenge: challenge, claims: claims}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad form", http.StatusBadRequest)
+		return
+	}
+	code := r.PostForm.Get("code")
+	idp.mu.Lock()
+	grant, ok := idp.grants[code]
+	delete(idp.grants, code)
+	idp.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		writeJSON(w, map[string]string{"error": "invalid_grant", "error_description": "pkce"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": idp.srv.URL,
+		"aud": testClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Minute).Unix(),
+	}
+	for k, v := range grant.claims {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = testIdPKeyID
+	signed, err := tok.SignedString(idp.key)
+	if err != nil {
+		idp.t.Errorf("sign id token: %v", err)
+		http.Error(w, "sign", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]interface{}{
+		"access_token": "idp-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+type ssoEnv struct {
+	db     *gorm.DB
+	idp    *mockIdP
+	router *gin.Engine
+}
+
+func newSSOEnv(t *testing.T) *ssoEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	if err := utils.InitSigningKeys(utils.KeyConfig{DevMode: true}); err != nil {
+		t.Fatalf("signing keys: %v", err)
+	}
+
+	env := &ssoEnv{
+		db: newTestDB(t,
+			&models.User{}, &models.UserIdentity{}, &models.OIDCLoginState{},
+			&models.AccountToken{}, &models.RefreshToken{}, &models.AuditLog{},
+		),
+		idp: newMockIdP(t),
+	}
+	provider := sso.New(sso.Config{
+		Issuer:      env.idp.srv.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://api.test/api/auth/oidc/callback",
+		RoleMapping: map[string]models.Role{
+			"staff":    models.RoleEngineer,
+			"managers": models.RoleManager,
+			"clients":  models.RoleCustomer,
+		},
+	})
+	cfg := AccountConfig{FrontendURL: testFrontendURL}
+
+	env.router = gin.New()
+	api := env.router.Group("/api")
+	api.GET("/auth/oidc/login", OIDCLoginHandler(env.db, provider))
+	api.GET("/auth/oidc/callback", OIDCCallbackHandler(env.db, provider, cfg))
+	api.POST("/auth/oidc/exchange", OIDCExchangeHandler(env.db))
+	return env
+}
+
+func (env *ssoEnv) do(req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+	return w
+}
+
+// ssoLogin — начатый вход: state из cookie и параметры запроса к IdP.
+type ssoLogin struct {
+	state, nonce, challenge string
+}
+
+func (env *ssoEnv) startLogin(t *testing.T) ssoLogin {
+	t.Helper()
+	w := env.do(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login?redirect=/tasks", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: status %d, body %s", w.Code, w.Body.String())
+	}
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), env.idp.srv.URL+"/authorize") {
+		t.Fatalf("login redirected to %q", w.Header().Get("Location"))
+	}
+	q := authURL.Query()
+	login := ssoLogin{state: q.Get("state"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge")}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == oidcStateCookie && c.Value != login.state {
+			t.Fatalf("state cookie %q does not match state %q", c.Value, login.state)
+		}
+	}
+	return login
+}
+
+// callback возвращает браузер с IdP; cookie — значение oidc_state в браузере.
+func (env *ssoEnv) callback(state, cookie, code string) *url.URL {
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/auth/oidc/callback?state="+url.QueryEscape(state)+"&code="+url.QueryEscape(code), nil)
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: cookie})
+	}
+	w := env.do(req)
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	return loc
+}
+
+// login проходит вход целиком и возвращает адрес, куда callback отправил браузер.
+func (env *ssoEnv) login(t *testing.T, claims jwt.MapClaims) *url.URL {
+	t.Helper()
+	l := env.startLogin(t)
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = l.nonce
+	}
+	return env.callback(l.state, l.state, env.idp.authorize(l.challenge, claims))
+}
+
+func (env *ssoEnv) exchange(code string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"code": code})
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/oidc/exchange", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	return env.do(req)
+}
+
+func ssoError(loc *url.URL) string {
+	if loc == nil || loc.Path != "/login" {
+		return ""
+	}
+	return loc.Query().Get("sso_error")
+}
+
+func identityClaims(sub, email string, groups ...string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":            sub,
+		"email":          email,
+		"email_verified": true,
+		"name":           "Пользователь " + sub,
+		"groups":         groups,
+	}
+}
+
+func TestOIDCLoginProvisionsUserWithHighestRole(t *testing.T) {
+	env := newSSOEnv(t)
+
+	loc := env.login(t, identityClaims("u-1", "lead@example.com", "staff", "managers", "clients"))
+	if loc == nil || loc.Path != "/sso/callback" || loc.Query().Get("code") == "" {
+		t.Fatalf("callback redirected to %v", loc)
+	}
+	if got := loc.Query().Get("redirect"); got != "/tasks" {
+		t.Fatalf("redirect = %q, want /tasks", got)
+	}
+
+	var user models.User
+	if err := env.db.Where("email = ?", "lead@example.com").Take(&user).Error; err != nil {
+		t.Fatalf("user not provisioned: %v", err)
+	}
+	if user.Role != models.RoleManager {
+		t.Fatalf("role = %s, want %s", user.Role, models.RoleManager)
+	}
+	var links int64
+	env.db.Model(&models.UserIdentity{}).Where("user_id = ? AND subject = ?", user.ID, "u-1").Count(&links)
+	if links != 1 {
+		t.Fatalf("identity links = %d, want 1", links)
+	}
+}
+
+func TestOIDCLoginWithoutMappedGroupIsRejected(t *testing.T) {
+	env := newSSOEnv(t)
+
+	if got := ssoError(env.login(t, identityClaims("u-2", "guest@example.com", "guests"))); got != "no_role" {
+		t.Fatalf("sso_error = %q, want no_role", got)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	env := newSSOEnv(t)
+	l := env.startLogin(t)
+	code := env.idp.authorize(l.challenge, jwt.MapClaims{"sub": "u-3", "nonce": l.nonce})
+
+	cases := []struct {
+		name, state, cookie string
+	}{
+		{"forged state", "forged", l.state},
+		{"foreign browser", l.state, "other-browser-state"},
+		{"missing cookie", l.state, ""},
+	}
+	for _, tc := range cases {
+		if got := ssoError(env.callback(tc.state, tc.cookie, code)); got != "invalid_state" {
+			t.Errorf("%s: sso_error = %q, want invalid_state", tc.name, got)
+		}
+	}
+}
+
+func TestOIDCCallbackRejectsReplayedState(t *testing.T) {
+	env := newSSOEnv(t)
+	l := env.startLogin(t)
+	claims := identityClaims("u-4", "eng@example.com", "staff")
+	claims["nonce"] = l.nonce
+	code := env.idp.authorize(l.challenge, claims)
+
+	if loc := env.callback(l.state, l.state, code); loc == nil || loc.Path != "/sso/callback" {
+		t.Fatalf("first callback redirected to %v", loc)
+	}
+	if got := ssoError(env.callback(l.state, l.state, code)); got != "invalid_state" {
+		t.Fatalf("replayed callback: sso_error = %q, want invalid_state", got)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	env := newSSOEnv(t)
+	claims := identityClaims("u-5", "nonce@example.com", "staff")
+	claims["nonce"] = "nonce-from-another-login"
+
+	if got := ssoError(env.login(t, claims)); got != "exchange_failed" {
+		t.Fatalf("sso_error = %q, want exchange_failed", got)
+	}
+	var users int64
+	env.db.Model(&models.User{}).Count(&users)
+	if users != 0 {
+		t.Fatalf("user created despite nonce mismatch")
+	}
+}
+
+func TestOIDCDoesNotLinkUnverifiedEmail(t *testing.T) {
+	env := newSSOEnv(t)
+	local := models.User{FullName: "Локальный", Email: "owner@example.com", Password: "x", Role: models.RoleAdmin}
+	if err := env.db.Create(&local).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	claims := identityClaims("attacker", "Owner@Example.com", "managers")
+	claims["email_verified"] = false
+	if got := ssoError(env.login(t, claims)); got != "email_unverified" {
+		t.Fatalf("sso_error = %q, want email_unverified", got)
+	}
+
+	var links int64
+	env.db.Model(&models.UserIdentity{}).Count(&links)
+	if links != 0 {
+		t.Fatalf("unverified email was linked to user %d", local.ID)
+	}
+}
+
+func TestOIDCExchangeCodeIsSingleUse(t *testing.T) {
+	env := newSSOEnv(t)
+	loc := env.login(t, identityClaims("u-6", "once@example.com", "staff"))
+	code := loc.Query().Get("code")
+	if code == "" {
+		t.Fatalf("callback redirected to %v", loc)
+	}
+
+	w := env.exchange(code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("first exchange: status %d, body %s", w.Code, w.Body.String())
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("first exchange returned no token: %s", w.Body.String())
+	}
+
+	if w := env.exchange(code); w.Code != http.StatusUnauthorized {
+		t.Fatalf("second exchange: status %d, want 401", w.Code)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+
+    "controlSystem/internal/models"
+    "gorm.io/gorm"
+)
+
+// statusTransitionCode переводит ошибку models.Status.CheckTransition в HTTP-код.
+func statusTransitionCode(err error) int {
+    switch {
+    case errors.Is(err, models.ErrUnknownStatus):
+        return http.StatusBadRequest
+    case errors.Is(err, models.ErrTransitionForbidden):
+        return http.StatusForbidden
+    default:
+        return http.StatusConflict
+    }
+}
+
+func statusHistory(defectID, actorID uint, from, to models.Status) *models.DefectHistory {
+    return &models.DefectHistory{
+        DefectID:   defectID,
+        ActorID:    actorID,
+        ActionType: "Изменение статуса дефекта",
+        ActionText: fmt.Sprintf("Статус дефекта изменён с '%s' на '%s'", from, to),
+    }
+}
+
+// syncDefectWithTask переносит статус задачи на дефект, из которого она была
+// создана, и пишет переход в историю дефекта. Вызывается внутри транзакции
+// вместе с сохранением задачи.
+func syncDefectWithTask(tx *gorm.DB, task *models.Task, actorID uint) error {
+    var defect models.Defect
+    q := tx.Model(&models.Defect{})
+    if task.RelatedDefectID != nil {
+        q = q.Where("id = ?", *task.RelatedDefectID)
+    } else {
+        q = q.Where("converted_to_task_id = ?", task.ID)
+    }
+    if err := q.Take(&defect).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil
+        }
+        return err
+    }
+
+    target := models.DefectStatusForTask(task.Status)
+    if defect.Status == target {
+        return nil
+    }
+
+    oldStatus := defect.Status
+    if err := tx.Model(&defect).Update("status", target).Error; err != nil {
+        return err
+    }
+    return tx.Create(statusHistory(defect.ID, actorID, oldStatus, target)).Error
+}
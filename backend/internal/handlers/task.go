@@ -0,0 +1,244 @@
+package handlers
+
+import (
+    "errors"
+    "net/http"
+    "time"
+
+    "controlSystem/internal/middleware"
+    "controlSystem/internal/models"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// errDefectReconverted — дефект удалённой задачи уже превращён в другую задачу.
+var errDefectReconverted = errors.New("defect already converted to another task")
+
+type taskUpdateInput struct {
+    Name        *string `json:"name"`
+    Description *string `json:"description"`
+    AssigneeID  *uint   `json:"assignee_id"`
+    DueDate     *string `json:"due_date"`
+}
+
+func UpdateTaskHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        var input taskUpdateInput
+        if err := c.ShouldBindJSON(&input); err != nil {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+
+        var task models.Task
+        if err := db.Scopes(visibleProjects(c, "project_id")).First(&task, c.Param("id")).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+            return
+        }
+
+        changes := newFieldChanges()
+        if input.Name != nil {
+            changes.set("name", "Название", task.Name, *input.Name)
+        }
+        if input.Description != nil {
+            changes.set("description", "Описание", task.Description, *input.Description)
+        }
+        if input.AssigneeID != nil {
+            changes.set("assignee_id", "Исполнитель", task.AssigneeID, input.AssigneeID)
+        }
+        if input.DueDate != nil {
+            var due *time.Time
+            if *input.DueDate != "" {
+                t, err := parseDate(*input.DueDate)
+                if err != nil {
+                    c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid due_date format"})
+                    return
+                }
+                due = &t
+            }
+            changes.set("due_date", "Срок", task.DueDate, due)
+        }
+        if changes.empty() {
+            c.JSON(http.StatusOK, task)
+            return
+        }
+
+        actorID := middleware.CurrentUserID(c)
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if input.AssigneeID != nil {
+                if err := checkTargetUser(tx, *input.AssigneeID, models.RoleEngineer, errInvalidAssignee); err != nil {
+                    return err
+                }
+            }
+            if err := tx.Model(&task).Updates(changes.updates).Error; err != nil {
+                return err
+            }
+            if input.AssigneeID != nil {
+                if err := ensureProjectMember(tx, task.ProjectID, *input.AssigneeID, models.ProjectRoleEngineer); err != nil {
+                    return err
+                }
+            }
+            return writeTaskHistory(tx, &task, actorID, "Изменение задачи", changes.String())
+        })
+        if errors.Is(err, errInvalidAssignee) {
+            c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+            return
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update task"})
+            return
+        }
+        c.JSON(http.StatusOK, task)
+    }
+}
+
+func DeleteTaskHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        var task models.Task
+        if err := db.Scopes(visibleProjects(c, "project_id")).First(&task, c.Param("id")).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+            return
+        }
+
+        actorID := middleware.CurrentUserID(c)
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Delete(&task).Error; err != nil {
+                return err
+            }
+            if err := writeTaskHistory(tx, &task, actorID, "Удаление задачи", task.Name); err != nil {
+                return err
+            }
+            return unlinkDefect(tx, &task, actorID)
+        })
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot delete task"})
+            return
+        }
+        c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+    }
+}
+
+func RestoreTaskHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        var task models.Task
+        if err := db.Unscoped().Scopes(visibleProjects(c, "project_id")).Where("deleted_at IS NOT NULL").First(&task, c.Param("id")).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "deleted task not found"})
+            return
+        }
+
+        actorID := middleware.CurrentUserID(c)
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Unscoped().Model(&task).Update("deleted_at", nil).Error; err != nil {
+                return err
+            }
+            if err := writeTaskHistory(tx, &task, actorID, "Восстановление задачи", task.Name); err != nil {
+                return err
+            }
+            return relinkDefect(tx, &task, actorID)
+        })
+        if errors.Is(err, errDefectReconverted) {
+            c.JSON(http.StatusConflict, gin.H{"error": "дефект задачи уже назначен в другую задачу"})
+            return
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot restore task"})
+            return
+        }
+        task.DeletedAt = gorm.DeletedAt{}
+        c.JSON(http.StatusOK, task)
+    }
+}
+
+// TaskHistoryHandler — журнал изменений задачи, включая удалённые задачи.
+func TaskHistoryHandler(db *gorm.DB) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        var task models.Task
+        if err := db.Unscoped().Scopes(visibleProjects(c, "project_id")).First(&task, c.Param("id")).Error; err != nil {
+            c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+            return
+        }
+        respondAudit(c, db, "task", task.ID)
+    }
+}
+
+// writeTaskHistory пишет изменение задачи в журнал и, если задача создана
+// из дефекта, в историю дефекта — там её видят пользователи.
+func writeTaskHistory(tx *gorm.DB, task *models.Task, actorID uint, action, details string) error {
+    if err := writeAudit(tx, actorID, "task", task.ID, action, details); err != nil {
+        return err
+    }
+    if task.RelatedDefectID == nil {
+        return nil
+    }
+    return tx.Create(&models.DefectHistory{
+        DefectID:   *task.RelatedDefectID,
+        ActorID:    actorID,
+        ActionType: action,
+        ActionText: details,
+    }).Error
+}
+
+// unlinkDefect возвращает дефект удалённой задачи в очередь менеджера:
+// снимает признак конвертации и сбрасывает статус на «Новая».
+func unlinkDefect(tx *gorm.DB, task *models.Task, actorID uint) error {
+    defect, err := linkedDefect(tx, task)
+    if err != nil || defect == nil || defect.ConvertedToTaskID == nil || *defect.ConvertedToTaskID != task.ID {
+        return err
+    }
+
+    oldStatus := defect.Status
+    if err := tx.Model(defect).Updates(map[string]interface{}{
+        "is_converted":         false,
+        "converted_to_task_id": nil,
+        "status":               models.StatusNew,
+    }).Error; err != nil {
+        return err
+    }
+    if oldStatus == models.StatusNew {
+        return nil
+    }
+    return tx.Create(statusHistory(defect.ID, actorID, oldStatus, models.StatusNew)).Error
+}
+
+// relinkDefect снова связывает дефект с восстановленной задачей, если его
+// не успели превратить в другую задачу.
+func relinkDefect(tx *gorm.DB, task *models.Task, actorID uint) error {
+    defect, err := linkedDefect(tx, task)
+    if err != nil || defect == nil {
+        return err
+    }
+    if defect.IsConverted && (defect.ConvertedToTaskID == nil || *defect.ConvertedToTaskID != task.ID) {
+        return errDefectReconverted
+    }
+
+    oldStatus := defect.Status
+    target := models.DefectStatusForTask(task.Status)
+    if err := tx.Model(defect).Updates(map[string]interface{}{
+        "is_converted":         true,
+        "converted_to_task_id": task.ID,
+        "status":               target,
+    }).Error; err != nil {
+        return err
+    }
+    if oldStatus == target {
+        return nil
+    }
+    return tx.Create(statusHistory(defect.ID, actorID, oldStatus, target)).Error
+}
+
+// linkedDefect блокирует дефект, из которого создана задача. Для задач без
+// дефекта и для удалённых дефектов возвращает nil.
+func linkedDefect(tx *gorm.DB, task *models.Task) (*models.Defect, error) {
+    if task.RelatedDefectID == nil {
+        return nil, nil
+    }
+    var defect models.Defect
+    err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&defect, *task.RelatedDefectID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &defect, nil
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"controlSystem/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// throttlePolicy — сколько ошибок прощается, как растёт задержка и когда
+// наступает полная блокировка.
+type throttlePolicy struct {
+	// FreeAttempts ошибок подряд не вызывают задержки.
+	FreeAttempts int
+	// После LockoutAfter ошибок ключ блокируется на Lockout.
+	LockoutAfter int
+	// Задержка после каждой следующей ошибки удваивается, начиная с BaseDelay.
+	BaseDelay time.Duration
+	Lockout   time.Duration
+	// Ошибки, между которыми прошло больше Window, считаются заново.
+	Window time.Duration
+}
+
+var (
+	loginAccountPolicy = throttlePolicy{FreeAttempts: 3, LockoutAfter: 10, BaseDelay: time.Second, Lockout: 15 * time.Minute, Window: time.Hour}
+	// С одного IP могут входить несколько человек (офис, NAT), поэтому порог выше.
+	loginIPPolicy = throttlePolicy{FreeAttempts: 10, LockoutAfter: 50, BaseDelay: time.Second, Lockout: 15 * time.Minute, Window: time.Hour}
+	// Код роли — 6 цифр, перебор должен упираться в блокировку очень быстро.
+	roleCodePolicy = throttlePolicy{FreeAttempts: 2, LockoutAfter: 5, BaseDelay: 2 * time.Second, Lockout: time.Hour, Window: 24 * time.Hour}
+	// Письма (подтверждение, сброс пароля) считаются каждым запросом, а не ошибкой.
+	mailAccountPolicy = throttlePolicy{FreeAttempts: 3, LockoutAfter: 6, BaseDelay: time.Minute, Lockout: time.Hour, Window: time.Hour}
+	mailIPPolicy      = throttlePolicy{FreeAttempts: 10, LockoutAfter: 30, BaseDelay: time.Minute, Lockout: time.Hour, Window: time.Hour}
+)
+
+func (p throttlePolicy) delay(failures int) time.Duration {
+	if failures >= p.LockoutAfter {
+		return p.Lockout
+	}
+	if failures <= p.FreeAttempts {
+		return 0
+	}
+	d := float64(p.BaseDelay) * math.Pow(2, float64(failures-p.FreeAttempts-1))
+	if d > float64(p.Lockout) {
+		return p.Lockout
+	}
+	return time.Duration(d)
+}
+
+type throttleKey struct {
+	Key    string
+	Policy throttlePolicy
+}
+
+func loginThrottleKeys(c *gin.Context, email string) []throttleKey {
+	return []throttleKey{
+		{Key: "login:ip:" + c.ClientIP(), Policy: loginIPPolicy},
+		{Key: "login:account:" + normalizeEmail(email), Policy: loginAccountPolicy},
+	}
+}
+
+func roleCodeThrottleKeys(c *gin.Context, email string) []throttleKey {
+	return []throttleKey{
+		{Key: "code:ip:" + c.ClientIP(), Policy: roleCodePolicy},
+		{Key: "code:account:" + normalizeEmail(email), Policy: roleCodePolicy},
+	}
+}
+
+func mailThrottleKeys(c *gin.Context, email string) []throttleKey {
+	return []throttleKey{
+		{Key: "mail:ip:" + c.ClientIP(), Policy: mailIPPolicy},
+		{Key: "mail:account:" + normalizeEmail(email), Policy: mailAccountPolicy},
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// throttleWait возвращает, сколько ещё ждать, если хотя бы один ключ заблокирован.
+func throttleWait(db *gorm.DB, keys []throttleKey) time.Duration {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Key
+	}
+	var rows []models.AuthThrottle
+	if err := db.Where("key IN ? AND locked_until > ?", names, time.Now()).Find(&rows).Error; err != nil {
+		log.Println("Failed to check auth throttle:", err)
+		return 0
+	}
+	var wait time.Duration
+	for _, r := range rows {
+		if d := time.Until(*r.LockedUntil); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// throttleFail засчитывает ошибку по каждому ключу и при необходимости
+// выставляет блокировку. Счётчик увеличивается одним запросом, чтобы
+// параллельные попытки не терялись.
+func throttleFail(db *gorm.DB, keys []throttleKey) {
+	now := time.Now()
+	for _, k := range keys {
+		var failures int
+		err := db.Raw(`INSERT INTO auth_throttles (key, failures, last_failure_at) VALUES (?, 1, ?)
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE WHEN auth_throttles.last_failure_at < ? THEN 1 ELSE auth_throttles.failures + 1 END,
+				last_failure_at = EXCLUDED.last_failure_at
+			RETURNING failures`, k.Key, now, now.Add(-k.Policy.Window)).Scan(&failures).Error
+		if err != nil {
+			log.Println("Failed to record auth failure:", err)
+			continue
+		}
+		if d := k.Policy.delay(failures); d > 0 {
+			if err := db.Model(&models.AuthThrottle{}).Where("key = ?", k.Key).Update("locked_until", now.Add(d)).Error; err != nil {
+				log.Println("Failed to lock auth throttle:", err)
+			}
+		}
+	}
+}
+
+// throttleReset сбрасывает счётчик после успешной попытки. Счётчик по IP
+// не сбрасывается: иначе удачный вход в свой аккаунт обнулял бы перебор чужих.
+func throttleReset(db *gorm.DB, key string) {
+	if err := db.Where("key = ?", key).Delete(&models.AuthThrottle{}).Error; err != nil {
+		log.Println("Failed to reset auth throttle:", err)
+	}
+}
+
+func tooManyAttempts(c *gin.Context, wait time.Duration) {
+	secs := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "слишком много попыток, повторите позже", "retry_after": secs})
+}
+
+func recordAuthFailure(db *gorm.DB, c *gin.Context, kind, email string, userID *uint, reason string) {
+	err := db.Create(&models.AuthFailure{
+		Kind:      kind,
+		Email:     normalizeEmail(email),
+		UserID:    userID,
+		IP:        c.ClientIP(),
+		Reason:    reason,
+		UserAgent: c.Request.UserAgent(),
+	}).Error
+	if err != nil {
+		log.Println("Failed to write auth failure log:", err)
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type userUpdateInput struct {
+	FullName *string `json:"full_name"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+}
+
+var errEmailTaken = errors.New("email already registered")
+
+// GetUserHandler — карточка пользователя для администратора.
+func GetUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		c.JSON(http.StatusOK, user.Profile())
+	}
+}
+
+// UpdateUserHandler меняет ФИО и почту пользователя. Новую почту нужно
+// подтвердить заново — пользователю уходит письмо со ссылкой.
+func UpdateUserHandler(db *gorm.DB, cfg AccountConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input userUpdateInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+
+		changes := newFieldChanges()
+		if input.FullName != nil {
+			name := strings.TrimSpace(*input.FullName)
+			if name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "full_name must not be empty"})
+				return
+			}
+			changes.set("full_name", "ФИО", user.FullName, name)
+		}
+		if input.Email != nil {
+			changes.set("email", "Email", user.Email, normalizeEmail(*input.Email))
+		}
+		_, emailChanged := changes.updates["email"]
+		if emailChanged {
+			changes.set("email_verified_at", "Почта подтверждена", user.EmailVerifiedAt, (*time.Time)(nil))
+		}
+		if changes.empty() {
+			c.JSON(http.StatusOK, user.Profile())
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if email, ok := changes.updates["email"]; ok {
+				var n int64
+				if err := tx.Model(&models.User{}).Where("lower(email) = ? AND id <> ?", email, user.ID).Count(&n).Error; err != nil {
+					return err
+				}
+				if n > 0 {
+					return errEmailTaken
+				}
+			}
+			if err := tx.Model(&user).Updates(changes.updates).Error; err != nil {
+				return err
+			}
+			return writeAudit(tx, middleware.CurrentUserID(c), "user", user.ID, "Изменение пользователя", changes.String())
+		})
+		if errors.Is(err, errEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			log.Println("Failed to update user:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
+			return
+		}
+		if emailChanged {
+			sendInBackground(func(ctx context.Context) {
+				sendVerificationEmail(ctx, db, cfg, user)
+			})
+		}
+		c.JSON(http.StatusOK, user.Profile())
+	}
+}
+
+// ChangeUserRoleHandler меняет роль пользователя. Роль записана в токене,
+// поэтому все его сессии завершаются — новая роль действует со следующего входа.
+func ChangeUserRoleHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Role models.Role `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if !input.Role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		actorID := middleware.CurrentUserID(c)
+		if user.ID == actorID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "нельзя изменить собственную роль"})
+			return
+		}
+		if user.Role == input.Role {
+			c.JSON(http.StatusOK, user.Profile())
+			return
+		}
+
+		old := user.Role
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Update("role", input.Role).Error; err != nil {
+				return err
+			}
+			if err := revokeUserSessions(tx, user.ID); err != nil {
+				return err
+			}
+			return writeAudit(tx, actorID, "user", user.ID, "Изменение роли", fmt.Sprintf("Роль: %s → %s", old, input.Role))
+		})
+		if err != nil {
+			log.Println("Failed to change user role:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot change role"})
+			return
+		}
+		c.JSON(http.StatusOK, user.Profile())
+	}
+}
+
+// DeactivateUserHandler блокирует учётную запись и завершает все её сессии.
+// Задачи пользователя остаются за ним — их переназначают отдельно.
+func DeactivateUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return setUserActive(db, false)
+}
+
+func ReactivateUserHandler(db *gorm.DB) gin.HandlerFunc {
+	return setUserActive(db, true)
+}
+
+func setUserActive(db *gorm.DB, active bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		actorID := middleware.CurrentUserID(c)
+		if !active && user.ID == actorID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "нельзя деактивировать самого себя"})
+			return
+		}
+		if (user.DeactivatedAt == nil) == active {
+			c.JSON(http.StatusOK, user.Profile())
+			return
+		}
+
+		var deactivatedAt *time.Time
+		action := "Активация пользователя"
+		if !active {
+			now := time.Now()
+			deactivatedAt = &now
+			action = "Деактивация пользователя"
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Update("deactivated_at", deactivatedAt).Error; err != nil {
+				return err
+			}
+			if !active {
+				if err := revokeUserSessions(tx, user.ID); err != nil {
+					return err
+				}
+			}
+			return writeAudit(tx, actorID, "user", user.ID, action, "")
+		})
+		if err != nil {
+			log.Println("Failed to change user activity:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
+			return
+		}
+		c.JSON(http.StatusOK, user.Profile())
+	}
+}
+
+// ReassignUserTasksHandler передаёт все незакрытые задачи пользователя
+// другому исполнителю — обычно при уходе инженера.
+func ReassignUserTasksHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			AssigneeID uint `json:"assignee_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var from, to models.User
+		if err := db.First(&from, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		if err := db.First(&to, input.AssigneeID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "новый исполнитель не найден"})
+			return
+		}
+		if to.ID == from.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "задачи уже назначены этому пользователю"})
+			return
+		}
+		if to.DeactivatedAt != nil || to.Role != models.RoleEngineer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "новым исполнителем может быть только активный инженер"})
+			return
+		}
+
+		actorID := middleware.CurrentUserID(c)
+		var tasks []models.Task
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("assignee_id = ? AND status <> ?", from.ID, models.StatusClosed).Find(&tasks).Error; err != nil {
+				return err
+			}
+			for i := range tasks {
+				task := &tasks[i]
+				if err := tx.Model(task).Update("assignee_id", to.ID).Error; err != nil {
+					return err
+				}
+				if err := ensureProjectMember(tx, task.ProjectID, to.ID, models.ProjectRoleEngineer); err != nil {
+					return err
+				}
+				details := fmt.Sprintf("Исполнитель: %s → %s", from.FullName, to.FullName)
+				if err := writeTaskHistory(tx, task, actorID, "Переназначение задачи", details); err != nil {
+					return err
+				}
+			}
+			return writeAudit(tx, actorID, "user", from.ID, "Переназначение задач",
+				fmt.Sprintf("Передано задач: %d, новый исполнитель: %s (id %d)", len(tasks), to.FullName, to.ID))
+		})
+		if err != nil {
+			log.Println("Failed to reassign tasks:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot reassign tasks"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reassigned": len(tasks)})
+	}
+}
+
+// UserHistoryHandler — журнал изменений учётной записи.
+func UserHistoryHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.Select("id").First(&user, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "пользователь не найден"})
+			return
+		}
+		respondAudit(c, db, "user", user.ID)
+	}
+}
@@ -3,26 +3,56 @@ package handlers
 import (
 	"net/http"
 
+	"controlSystem/internal/middleware"
 	"controlSystem/internal/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+var userListSpec = listSpec{
+	Filters: map[string]string{"role": "role"},
+	Sortable: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+		"full_name":  "full_name",
+		"email":      "email",
+	},
+	DefaultSort: "full_name",
+}
+
+// ListUsersHandler — список пользователей; ?active=true|false фильтрует по деактивации.
+// Полные профили (почта, даты) видит только администратор, остальным — PublicUser.
 func ListUsersHandler(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role := c.Query("role")
+		if role := c.Query("role"); role != "" && !models.Role(role).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		q := db.Model(&models.User{})
+		switch c.Query("active") {
+		case "true":
+			q = q.Where("deactivated_at IS NULL")
+		case "false":
+			q = q.Where("deactivated_at IS NOT NULL")
+		}
+
 		var users []models.User
-		if role != "" {
-			if err := db.Where("role = ?", role).Find(&users).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось получить пользователей"})
-				return
-			}
-		} else {
-			if err := db.Find(&users).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "не удалось получить пользователей"})
-				return
+		if !paginate(c, q, userListSpec, &users) {
+			return
+		}
+		if middleware.CurrentRole(c) != models.RoleAdmin {
+			public := make([]models.PublicUser, len(users))
+			for i, u := range users {
+				public[i] = u.Public()
 			}
+			c.JSON(http.StatusOK, public)
+			return
+		}
+		profiles := make([]models.UserProfile, len(users))
+		for i, u := range users {
+			profiles[i] = u.Profile()
 		}
-		c.JSON(http.StatusOK, users)
+		c.JSON(http.StatusOK, profiles)
 	}
 }
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+    "errors"
+
+    "controlSystem/internal/middleware"
+    "controlSystem/internal/models"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+var (
+    errInvalidAssignee = errors.New("исполнителем может быть только активный инженер")
+    errInvalidManager  = errors.New("менеджером проекта может быть только активный менеджер")
+    errInvalidCustomer = errors.New("заказчиком проекта может быть только активный заказчик")
+)
+
+// visibleProjects возвращает scope, ограничивающий выборку проектами,
+// в которых участвует текущий пользователь. column — колонка с ID проекта
+// в запросе. Админ видит все проекты; остальные — только проекты, где они
+// состоят в project_members. Менеджер и заказчик проекта всегда его участники.
+func visibleProjects(c *gin.Context, column string) func(*gorm.DB) *gorm.DB {
+    userID := middleware.CurrentUserID(c)
+    role := middleware.CurrentRole(c)
+    return func(q *gorm.DB) *gorm.DB {
+        if role == models.RoleAdmin {
+            return q
+        }
+        return q.Where(column+" IN (SELECT project_id FROM project_members WHERE user_id = ?)", userID)
+    }
+}
+
+// projectVisible сообщает, участвует ли текущий пользователь в проекте.
+func projectVisible(c *gin.Context, db *gorm.DB, projectID uint) bool {
+    var count int64
+    db.Model(&models.Project{}).Where("id = ?", projectID).Scopes(visibleProjects(c, "id")).Count(&count)
+    return count > 0
+}
+
+// defectVisible загружает дефект, если он принадлежит видимому пользователю проекту.
+func defectVisible(c *gin.Context, db *gorm.DB, defectID interface{}) (models.Defect, bool) {
+    var defect models.Defect
+    err := db.Scopes(visibleProjects(c, "project_id")).First(&defect, defectID).Error
+    return defect, err == nil
+}
+
+// ensureProjectMember добавляет пользователя в проект, если его там ещё нет.
+// Пустой userID пропускается.
+func ensureProjectMember(tx *gorm.DB, projectID, userID uint, role models.ProjectRole) error {
+    if userID == 0 {
+        return nil
+    }
+    member := models.ProjectMember{ProjectID: projectID, UserID: userID}
+    return tx.Where(&member).Attrs(models.ProjectMember{Role: role}).FirstOrCreate(&member).Error
+}
+
+// checkTargetUser проверяет, что пользователь, которого назначают на проект
+// или задачу, существует, активен и имеет роль role. Иначе возвращает fail —
+// его текст уходит клиенту с кодом 400.
+func checkTargetUser(tx *gorm.DB, userID uint, role models.Role, fail error) error {
+    var user models.User
+    err := tx.Select("id", "role", "deactivated_at").First(&user, userID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return fail
+    }
+    if err != nil {
+        return err
+    }
+    if user.DeactivatedAt != nil || user.Role != role {
+        return fail
+    }
+    return nil
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Log печатает письма в лог сервера — для разработки.
+type Log struct{}
+
+func NewLog() *Log { return &Log{} }
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// File сохраняет каждое письмо отдельным .eml-файлом в каталоге — удобно
+// для тестов и стендов без почтового сервера.
+type File struct {
+	dir  string
+	from string
+}
+
+func NewFile(dir, from string) (*File, error) {
+	if dir == "" {
+		dir = "./mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if from == "" {
+		from = "noreply@localhost"
+	}
+	return &File{dir: dir, from: from}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), hex.EncodeToString(b))
+	return os.WriteFile(filepath.Join(f.dir, name), render(f.from, msg), 0o644)
+}
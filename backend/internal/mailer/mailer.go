@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string // text/plain, UTF-8
+}
+
+// Mailer отправляет служебные письма (подтверждение почты, сброс пароля).
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Backend string // "smtp", "file" или "log"
+
+	From string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Dir — каталог, куда file-бэкенд складывает письма в формате .eml.
+	Dir string
+}
+
+func New(cfg Config) (Mailer, error) {
+	switch cfg.Backend {
+	case "", "log":
+		return NewLog(), nil
+	case "file":
+		return NewFile(cfg.Dir, cfg.From)
+	case "smtp":
+		return NewSMTP(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+    "net/http"
+    "time"
+
+    "controlSystem/internal/models"
+    "controlSystem/internal/utils"
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+// APIKeyHeader — заголовок, в котором интеграции передают API-ключ вместо Bearer-токена.
+const APIKeyHeader = "X-API-Key"
+
+// lastUsedGranularity — чаще этого last_used_at не обновляется, чтобы не писать в БД на каждый запрос.
+const lastUsedGranularity = time.Minute
+
+func authenticateAPIKey(c *gin.Context, db *gorm.DB, raw string) {
+    var key models.APIKey
+    if err := db.Where("key_hash = ?", utils.HashToken(raw)).Take(&key).Error; err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+        c.Abort()
+        return
+    }
+    var owner models.User
+    if err := db.Select("id", "role", "is_service", "deactivated_at").First(&owner, key.UserID).Error; err != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+        c.Abort()
+        return
+    }
+    now := time.Now()
+    if !key.Active(now) || !owner.IsService {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+        c.Abort()
+        return
+    }
+    if owner.DeactivatedAt != nil {
+        c.JSON(http.StatusUnauthorized, gin.H{"error": "account deactivated"})
+        c.Abort()
+        return
+    }
+
+    db.Model(&models.APIKey{}).
+        Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", key.ID, now.Add(-lastUsedGranularity)).
+        Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": c.ClientIP()})
+
+    c.Set("userID", key.UserID)
+    c.Set("role", owner.Role)
+    c.Set("apiKey", &key)
+
+    c.Next()
+}
+
+// CurrentAPIKey возвращает ключ, которым аутентифицирован запрос, или nil для обычной сессии.
+func CurrentAPIKey(c *gin.Context) *models.APIKey {
+    v, _ := c.Get("apiKey")
+    k, _ := v.(*models.APIKey)
+    return k
+}
+
+// RequireUserSession закрывает маршрут для API-ключей: выход, смена пароля
+// и личные настройки имеют смысл только для сессии человека.
+func RequireUserSession() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if CurrentAPIKey(c) != nil {
+            c.JSON(http.StatusForbidden, gin.H{"error": "недоступно для API-ключей"})
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
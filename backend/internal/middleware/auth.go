@@ -4,14 +4,20 @@ import (
     "net/http"
     "strings"
 
+    "controlSystem/internal/models"
     "controlSystem/internal/utils"
     "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
     return func(c *gin.Context) {
         authHeader := c.GetHeader("Authorization")
         if authHeader == "" {
+            if key := c.GetHeader(APIKeyHeader); key != "" {
+                authenticateAPIKey(c, db, key)
+                return
+            }
             c.JSON(http.StatusUnauthorized, gin.H{"error": "missing auth header"})
             c.Abort()
             return
@@ -32,8 +38,42 @@ func AuthMiddleware() gin.HandlerFunc {
             return
         }
 
+        if reason := rejectToken(db, claims); reason != "" {
+            c.JSON(http.StatusUnauthorized, gin.H{"error": reason})
+            c.Abort()
+            return
+        }
+
         c.Set("userID", claims.UserID)
+        c.Set("role", models.Role(claims.Role))
+        c.Set("claims", claims)
 
         c.Next()
     }
 }
+
+// rejectToken возвращает причину отказа, если токен отозван при выходе (по jti),
+// выпущен до того, как пользователь завершил все сессии (другое поколение),
+// или пользователь деактивирован. Токены без jti и iat не принимаются.
+func rejectToken(db *gorm.DB, claims *utils.Claims) string {
+    if claims.ID == "" || claims.IssuedAt == nil {
+        return "invalid token"
+    }
+
+    var n int64
+    if err := db.Model(&models.RevokedToken{}).Where("jti = ?", claims.ID).Count(&n).Error; err != nil || n > 0 {
+        return "token revoked"
+    }
+
+    var user models.User
+    if err := db.Select("id", "session_generation", "deactivated_at").First(&user, claims.UserID).Error; err != nil {
+        return "invalid token"
+    }
+    if user.DeactivatedAt != nil {
+        return "account deactivated"
+    }
+    if claims.Generation != user.SessionGeneration {
+        return "token revoked"
+    }
+    return ""
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+    "net/http"
+
+    "controlSystem/internal/models"
+    "controlSystem/internal/utils"
+    "github.com/gin-gonic/gin"
+)
+
+// RequirePermission проверяет действие по матрице models.RolePermissions.
+// Для API-ключа право должно быть ещё и в его scopes.
+func RequirePermission(p models.Permission) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        if !CurrentRole(c).Can(p) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "недостаточно прав"})
+            c.Abort()
+            return
+        }
+        if key := CurrentAPIKey(c); key != nil && !key.HasScope(p) {
+            c.JSON(http.StatusForbidden, gin.H{"error": "API-ключу не выдано право " + string(p)})
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+func CurrentRole(c *gin.Context) models.Role {
+    role, _ := c.Get("role")
+    r, _ := role.(models.Role)
+    return r
+}
+
+func CurrentUserID(c *gin.Context) uint {
+    userID, _ := c.Get("userID")
+    id, _ := userID.(uint)
+    return id
+}
+
+// CurrentClaims возвращает claims access-токена текущего запроса.
+func CurrentClaims(c *gin.Context) *utils.Claims {
+    claims, _ := c.Get("claims")
+    cl, _ := claims.(*utils.Claims)
+    return cl
+}
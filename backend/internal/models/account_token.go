@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type AccountTokenPurpose string
+
+const (
+	PurposeVerifyEmail   AccountTokenPurpose = "verify_email"
+	PurposeResetPassword AccountTokenPurpose = "reset_password"
+	// Короткоживущий код, по которому фронтенд после входа через SSO получает токены.
+	PurposeSSOExchange AccountTokenPurpose = "sso_exchange"
+)
+
+// AccountToken — одноразовый токен из письма (подтверждение почты, сброс
+// пароля). Хранится только хэш; после использования заполняется UsedAt.
+type AccountToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID    uint                `gorm:"not null;index"`
+	Purpose   AccountTokenPurpose `gorm:"type:varchar(32);not null"`
+	TokenHash string              `gorm:"type:varchar(64);uniqueIndex;not null"`
+
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
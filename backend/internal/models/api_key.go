@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// APIKey — ключ доступа для интеграций (CI, BIM). Действует от имени
+// служебного пользователя и только в пределах Scopes; хранится хэш ключа.
+type APIKey struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Name string `gorm:"not null" json:"name"`
+	// Prefix — начало ключа, по которому его можно узнать в списке.
+	Prefix  string `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash string `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+
+	UserID uint       `gorm:"not null;index" json:"user_id"`
+	User   PublicUser `gorm:"foreignKey:UserID" json:"user"`
+
+	Scopes []Permission `gorm:"serializer:json;type:text;not null" json:"scopes"`
+
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `gorm:"type:varchar(64)" json:"last_used_ip"`
+
+	CreatedByID *uint `json:"created_by_id"`
+}
+
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+func (k APIKey) HasScope(p Permission) bool {
+	for _, s := range k.Scopes {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AuditLog — журнал изменений для сущностей, у которых нет собственной
+// истории (проекты, задачи, пользователи). Изменения дефектов пишутся в DefectHistory.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	ActorID uint       `gorm:"index" json:"actor_id"`
+	Actor   PublicUser `gorm:"foreignKey:ActorID" json:"actor"`
+
+	EntityType string `gorm:"type:varchar(30);index:idx_audit_entity" json:"entity_type"`
+	EntityID   uint   `gorm:"index:idx_audit_entity" json:"entity_id"`
+	Action     string `json:"action"`
+	Details    string `gorm:"type:text" json:"details"`
+}
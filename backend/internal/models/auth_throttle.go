@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// AuthThrottle — счётчик неудачных попыток по ключу ("login:ip:…",
+// "login:account:…", "code:ip:…"). Пока LockedUntil в будущем, попытки
+// с этим ключом отклоняются без проверки пароля или кода.
+type AuthThrottle struct {
+	Key           string    `gorm:"primaryKey;type:varchar(320)"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+// AuthFailure — журнал неудачных попыток входа и ввода кода роли.
+type AuthFailure struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Kind   string `gorm:"type:varchar(20);not null" json:"kind"` // login или role_code
+	Email  string `gorm:"index" json:"email"`
+	UserID *uint  `json:"user_id"`
+	IP     string `gorm:"type:varchar(64);index" json:"ip"`
+	// Reason: invalid_credentials, locked, invalid_code.
+	Reason    string `gorm:"type:varchar(30)" json:"reason"`
+	UserAgent string `json:"user_agent"`
+}
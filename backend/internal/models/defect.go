@@ -1,24 +1,29 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Defect struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Title       string `gorm:"not null" json:"title"`
 	Description string `gorm:"type:text" json:"description"`
 
-	ProjectID uint   `json:"project_id"`
+	ProjectID uint   `gorm:"index" json:"project_id"`
 	Project   Project `gorm:"foreignKey:ProjectID" json:"project"`
 
-	InitiatorID uint `json:"initiator_id"`
-	Initiator   User `gorm:"foreignKey:InitiatorID" json:"initiator"`
+	InitiatorID uint `gorm:"index" json:"initiator_id"`
+	Initiator   PublicUser `gorm:"foreignKey:InitiatorID" json:"initiator"`
 
-	Status string `gorm:"default:'Новая'" json:"status"`
+	Status Status `gorm:"default:'Новая';index" json:"status"`
     Files       []DefectFile `gorm:"foreignKey:DefectID" json:"files"`
-	DueDate *time.Time `json:"due_date"`
+	DueDate *time.Time `gorm:"index" json:"due_date"`
     History []DefectHistory `gorm:"foreignKey:DefectID" json:"history"`
 
 	IsConverted       bool  `gorm:"default:false" json:"is_converted"`
@@ -26,21 +31,43 @@ type Defect struct {
 }
 
 type DefectFile struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	DefectID uint   `json:"defect_id"`
+	DefectID uint   `gorm:"index:idx_defect_file_checksum" json:"defect_id"`
 	FileName string `json:"file_name"`
 	FilePath string `json:"file_path"`
+
+	// Уменьшенные копии изображений; пустые для остальных типов файлов.
+	ThumbnailPath string `json:"thumbnail_path,omitempty"`
+	PreviewPath   string `json:"preview_path,omitempty"`
+
+	Size     int64  `json:"size"`
+	MimeType string `gorm:"type:varchar(100)" json:"mime_type"`
+	// SHA-256 содержимого в hex; по нему находятся повторные загрузки в тот же дефект.
+	Checksum string `gorm:"type:varchar(64);index:idx_defect_file_checksum" json:"checksum"`
+
+	UploaderID *uint `json:"uploader_id"`
+	Uploader   *PublicUser `gorm:"foreignKey:UploaderID" json:"uploader,omitempty"`
+
+	// Комментарий, вместе с которым был загружен файл.
+	HistoryID *uint `json:"history_id"`
+
+	// Версии: при замене создаётся новая запись, старая остаётся доступной
+	// для скачивания и ссылается на замену через SupersededByID.
+	Version           int   `gorm:"not null;default:1" json:"version"`
+	PreviousVersionID *uint `json:"previous_version_id"`
+	SupersededByID    *uint `gorm:"index" json:"superseded_by_id"`
 }
 
 type DefectHistory struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 
-	DefectID   uint   `json:"defect_id"`
-	ActorID    uint   `json:"actor_id"`
-	Actor     User      `gorm:"foreignKey:ActorID" json:"actor"`
-	ActionType string `json:"action_type"`
-	ActionText string `json:"action_text"`
+	DefectID   uint       `json:"defect_id"`
+	ActorID    uint       `json:"actor_id"`
+	Actor      PublicUser `gorm:"foreignKey:ActorID" json:"actor"`
+	ActionType string     `json:"action_type"`
+	ActionText string     `json:"action_text"`
 }
@@ -0,0 +1,76 @@
+package models
+
+type Permission string
+
+const (
+	PermProjectsRead  Permission = "projects:read"
+	PermProjectsWrite Permission = "projects:write"
+
+	PermDefectsRead    Permission = "defects:read"
+	PermDefectsWrite   Permission = "defects:write"
+	PermDefectsAssign  Permission = "defects:assign"
+	PermDefectsComment Permission = "defects:comment"
+	PermDefectsHistory Permission = "defects:history"
+	PermDefectsEdit    Permission = "defects:edit"
+	PermFilesRead      Permission = "files:read"
+
+	PermTasksRead    Permission = "tasks:read"
+	PermTasksReadOwn Permission = "tasks:read-own"
+	PermTasksStatus  Permission = "tasks:status"
+	PermTasksWrite   Permission = "tasks:write"
+
+	PermReportsRead Permission = "reports:read"
+	PermUsersRead   Permission = "users:read"
+	PermRatingRead  Permission = "rating:read"
+	PermSearch      Permission = "search:read"
+
+	PermInvitesManage Permission = "invites:manage"
+	PermUsersManage   Permission = "users:manage"
+	PermAPIKeysManage Permission = "apikeys:manage"
+)
+
+// RolePermissions — матрица прав: какие действия разрешены каждой роли.
+var RolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermProjectsRead, PermProjectsWrite,
+		PermDefectsRead, PermDefectsWrite, PermDefectsAssign, PermDefectsComment, PermDefectsHistory, PermFilesRead,
+		PermDefectsEdit,
+		PermTasksRead, PermTasksReadOwn, PermTasksStatus, PermTasksWrite,
+		PermReportsRead, PermUsersRead, PermRatingRead,
+		PermSearch,
+		PermInvitesManage, PermUsersManage, PermAPIKeysManage,
+	},
+	RoleManager: {
+		PermProjectsRead, PermProjectsWrite,
+		PermDefectsRead, PermDefectsWrite, PermDefectsAssign, PermDefectsComment, PermDefectsHistory, PermFilesRead,
+		PermDefectsEdit,
+		PermTasksRead, PermTasksReadOwn, PermTasksStatus, PermTasksWrite,
+		PermReportsRead, PermUsersRead, PermRatingRead,
+		PermSearch,
+	},
+	RoleEngineer: {
+		PermProjectsRead,
+		PermDefectsWrite, PermDefectsComment, PermDefectsHistory, PermFilesRead,
+		PermTasksReadOwn, PermTasksStatus,
+		PermRatingRead, PermSearch,
+	},
+	RoleCustomer: {
+		PermProjectsRead,
+		PermDefectsWrite, PermDefectsHistory, PermFilesRead,
+		PermReportsRead, PermRatingRead, PermSearch,
+	},
+}
+
+// Valid — такое право есть в матрице (у администратора есть все права).
+func (p Permission) Valid() bool {
+	return RoleAdmin.Can(p)
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, allowed := range RolePermissions[r] {
+		if allowed == p {
+			return true
+		}
+	}
+	return false
+}
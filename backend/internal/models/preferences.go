@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// UserPreferences — личные настройки пользователя. Запись создаётся при
+// первом сохранении; до этого действуют значения по умолчанию.
+type UserPreferences struct {
+	UserID    uint      `gorm:"primaryKey" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Language string `gorm:"type:varchar(8);not null;default:'ru'" json:"language"`
+
+	EmailNotifications   bool `gorm:"not null" json:"email_notifications"`
+	NotifyOnAssign       bool `gorm:"not null" json:"notify_on_assign"`
+	NotifyOnStatusChange bool `gorm:"not null" json:"notify_on_status_change"`
+	NotifyOnComment      bool `gorm:"not null" json:"notify_on_comment"`
+}
+
+// SupportedLanguages — языки интерфейса.
+var SupportedLanguages = map[string]bool{"ru": true, "en": true}
+
+func DefaultPreferences(userID uint) UserPreferences {
+	return UserPreferences{
+		UserID:               userID,
+		Language:             "ru",
+		EmailNotifications:   true,
+		NotifyOnAssign:       true,
+		NotifyOnStatusChange: true,
+		NotifyOnComment:      true,
+	}
+}
@@ -8,21 +8,22 @@ import (
 
 type Project struct {
 	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	CreatedAt   time.Time      `json:"created_at"`
+	CreatedAt   time.Time      `gorm:"index" json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Name        string `gorm:"not null" json:"name"`
+	Name        string `gorm:"not null;index" json:"name"`
 	Description string `gorm:"type:text" json:"description"`
 
-	ManagerID   uint `gorm:"not null" json:"manager_id"`
-	Manager     User `gorm:"foreignKey:ManagerID" json:"manager"`
+	ManagerID   uint `gorm:"not null;index" json:"manager_id"`
+	Manager     PublicUser `gorm:"foreignKey:ManagerID" json:"manager"`
 
-	CustomerID  uint `gorm:"not null" json:"customer_id"`
-	Customer    User `gorm:"foreignKey:CustomerID" json:"customer"`
+	CustomerID  uint `gorm:"not null;index" json:"customer_id"`
+	Customer    PublicUser `gorm:"foreignKey:CustomerID" json:"customer"`
 
 	Active      bool `gorm:"default:true" json:"active"`
 
 	Tasks       []Task `gorm:"foreignKey:ProjectID" json:"tasks"`
+	Members     []ProjectMember `gorm:"foreignKey:ProjectID" json:"members,omitempty"`
 }
 
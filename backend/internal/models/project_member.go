@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// ProjectRole — роль пользователя внутри конкретного проекта.
+type ProjectRole string
+
+const (
+	ProjectRoleManager  ProjectRole = "manager"
+	ProjectRoleEngineer ProjectRole = "engineer"
+	ProjectRoleCustomer ProjectRole = "customer"
+	ProjectRoleObserver ProjectRole = "observer"
+)
+
+func (r ProjectRole) Valid() bool {
+	switch r {
+	case ProjectRoleManager, ProjectRoleEngineer, ProjectRoleCustomer, ProjectRoleObserver:
+		return true
+	}
+	return false
+}
+
+type ProjectMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ProjectID uint       `gorm:"not null;uniqueIndex:idx_project_member" json:"project_id"`
+	UserID    uint       `gorm:"not null;uniqueIndex:idx_project_member;index" json:"user_id"`
+	User      PublicUser `gorm:"foreignKey:UserID" json:"user"`
+
+	Role ProjectRole `gorm:"type:varchar(20);not null" json:"role"`
+}
@@ -2,11 +2,49 @@ package models
 
 import "time"
 
+// RoleCode — код приглашения, без которого нельзя зарегистрироваться
+// инженером или менеджером. Коды из CODE_MANAGER/CODE_ENGINEER создаются при
+// первом запуске без ограничений; остальные выпускает администратор.
 type RoleCode struct {
-	ID        uint      `gorm:"primaryKey"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 
-	Role Role   `gorm:"type:varchar(20);uniqueIndex;not null"`
-	Code string `gorm:"type:varchar(10);uniqueIndex;not null"`
+	Role Role   `gorm:"type:varchar(20);index:idx_role_code_role;not null" json:"role"`
+	Code string `gorm:"type:varchar(16);uniqueIndex;not null" json:"code"`
+
+	// MaxUses = 0 — без ограничения числа регистраций.
+	MaxUses   int        `gorm:"not null;default:0" json:"max_uses"`
+	Uses      int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+
+	// Если задан Email, код примет только регистрация с этим адресом.
+	Email string `json:"email,omitempty"`
+	// Зарегистрированный по коду пользователь сразу становится участником проекта.
+	ProjectID *uint    `gorm:"index" json:"project_id"`
+	Project   *Project `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+
+	CreatedByID *uint       `json:"created_by_id"`
+	CreatedBy   *PublicUser `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+
+	Redemptions []RoleCodeRedemption `json:"redemptions,omitempty"`
+}
+
+// Usable — код не отозван, не истёк и не исчерпан.
+func (rc RoleCode) Usable(now time.Time) bool {
+	if rc.RevokedAt != nil || (rc.ExpiresAt != nil && now.After(*rc.ExpiresAt)) {
+		return false
+	}
+	return rc.MaxUses == 0 || rc.Uses < rc.MaxUses
+}
+
+// RoleCodeRedemption — кто и когда зарегистрировался по коду.
+type RoleCodeRedemption struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	RoleCodeID uint       `gorm:"not null;index" json:"role_code_id"`
+	UserID     uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	User       PublicUser `gorm:"foreignKey:UserID" json:"user"`
 }
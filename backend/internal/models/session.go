@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RefreshToken — серверная запись о refresh-токене. Хранится только хэш.
+// Все токены одной сессии (цепочка ротаций) имеют общий SessionID.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time
+
+	UserID    uint   `gorm:"not null;index"`
+	SessionID string `gorm:"type:varchar(64);not null;index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex;not null"`
+
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	// Заполняется при ротации; повторное предъявление такого токена означает
+	// его утечку, и вся сессия отзывается.
+	ReplacedByID *uint
+
+	UserAgent string
+	IP        string `gorm:"type:varchar(64)"`
+}
+
+// RevokedToken — отозванный access-токен (jti). Запись нужна только до
+// истечения самого токена.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;type:varchar(64)"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
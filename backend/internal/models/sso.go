@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserIdentity связывает пользователя с учётной записью во внешнем IdP.
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID  uint   `gorm:"not null;index" json:"user_id"`
+	Issuer  string `gorm:"not null;uniqueIndex:idx_identity_subject" json:"issuer"`
+	Subject string `gorm:"not null;uniqueIndex:idx_identity_subject" json:"subject"`
+	Email   string `json:"email"`
+}
+
+// OIDCLoginState — незавершённый вход через IdP: state, nonce и PKCE
+// code_verifier живут до callback, но не дольше ExpiresAt.
+type OIDCLoginState struct {
+	StateHash  string `gorm:"primaryKey;type:varchar(64)"`
+	CreatedAt  time.Time
+	Nonce      string    `gorm:"not null"`
+	Verifier   string    `gorm:"not null"`
+	RedirectTo string    `gorm:"not null;default:'/'"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+}
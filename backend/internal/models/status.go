@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Status — общий статус дефекта и задачи.
+type Status string
+
+const (
+	StatusNew        Status = "Новая"
+	StatusInProgress Status = "В работе"
+	StatusReview     Status = "На проверке"
+	StatusClosed     Status = "Закрыта"
+)
+
+var (
+	ErrUnknownStatus       = errors.New("неизвестный статус")
+	ErrIllegalTransition   = errors.New("недопустимый переход статуса")
+	ErrTransitionForbidden = errors.New("переход статуса запрещён для роли")
+)
+
+type statusTransition struct {
+	From Status
+	To   Status
+}
+
+// statusTransitions — граф переходов и роли, которым разрешён каждый переход.
+// Инженер может только продвигать работу вперёд; вернуть задачу в работу,
+// закрыть или переоткрыть её может только менеджер.
+var statusTransitions = map[statusTransition][]Role{
+	{StatusNew, StatusInProgress}:    {RoleAdmin, RoleManager, RoleEngineer},
+	{StatusNew, StatusClosed}:        {RoleAdmin, RoleManager},
+	{StatusInProgress, StatusReview}: {RoleAdmin, RoleManager, RoleEngineer},
+	{StatusReview, StatusInProgress}: {RoleAdmin, RoleManager},
+	{StatusReview, StatusClosed}:     {RoleAdmin, RoleManager},
+	{StatusClosed, StatusInProgress}: {RoleAdmin, RoleManager},
+}
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusNew, StatusInProgress, StatusReview, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// CheckTransition проверяет, может ли пользователь с ролью role перевести
+// статус из s в to. Ошибки оборачивают ErrUnknownStatus, ErrIllegalTransition
+// или ErrTransitionForbidden.
+func (s Status) CheckTransition(to Status, role Role) error {
+	if !to.Valid() {
+		return fmt.Errorf("%w: '%s'", ErrUnknownStatus, to)
+	}
+	roles, ok := statusTransitions[statusTransition{s, to}]
+	if !ok {
+		return fmt.Errorf("%w: '%s' → '%s'", ErrIllegalTransition, s, to)
+	}
+	for _, r := range roles {
+		if r == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: '%s' → '%s' (%s)", ErrTransitionForbidden, s, to, role)
+}
+
+// DefectStatusForTask возвращает статус, который должен иметь дефект,
+// превращённый в задачу со статусом task. Только что созданная задача уже
+// означает, что дефект взят в работу.
+func DefectStatusForTask(task Status) Status {
+	if task == StatusNew {
+		return StatusInProgress
+	}
+	return task
+}
@@ -8,23 +8,23 @@ import (
 
 type Task struct {
 	ID        uint           `gorm:"primaryKey" json:"id"`
-	CreatedAt time.Time      `json:"created_at"`
+	CreatedAt time.Time      `gorm:"index" json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Name        string `gorm:"not null" json:"name"`
 	Description string `gorm:"type:text" json:"description"`
 
-	Status    string `gorm:"default:'Новая'" json:"status"`
-	ProjectID uint   `json:"project_id"`
+	Status    Status `gorm:"default:'Новая';index" json:"status"`
+	ProjectID uint   `gorm:"index" json:"project_id"`
 
-	CreatorID uint `json:"creator_id"`
-	Creator   User `gorm:"foreignKey:CreatorID" json:"creator"`
+	CreatorID uint       `json:"creator_id"`
+	Creator   PublicUser `gorm:"foreignKey:CreatorID" json:"creator"`
 
-	AssigneeID *uint `json:"assignee_id"`
-	Assignee   *User `gorm:"foreignKey:AssigneeID" json:"assignee"`
+	AssigneeID *uint       `gorm:"index" json:"assignee_id"`
+	Assignee   *PublicUser `gorm:"foreignKey:AssigneeID" json:"assignee"`
 
-	DueDate *time.Time `json:"due_date"`
+	DueDate *time.Time `gorm:"index" json:"due_date"`
 
 	// связь с дефектом
 	RelatedDefectID *uint   `json:"related_defect_id"`
@@ -1,9 +1,6 @@
 package models
 
-import (
-	"time"
-
-)
+import "time"
 
 type Role string
 
@@ -14,13 +11,77 @@ const (
 	RoleCustomer Role = "customer"
 )
 
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleManager, RoleEngineer, RoleCustomer:
+		return true
+	}
+	return false
+}
+
 type User struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	CreatedAt time.Time `json:"created_at"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	FullName string `gorm:"not null" json:"full_name"`
+	FullName string `gorm:"not null;index" json:"full_name"`
 	Email    string `gorm:"uniqueIndex;not null" json:"email"`
 	Password string `gorm:"not null" json:"-"`
-	Role     Role      `gorm:"type:varchar(20);not null" json:"role"`
+	Role     Role      `gorm:"type:varchar(20);not null;index" json:"role"`
+
+	// Поколение сессий: растёт при "выйти на всех устройствах", смене пароля
+	// и роли. Access-токены с другим поколением недействительны.
+	SessionGeneration uint `gorm:"not null;default:0" json:"-"`
+	// Пока почта не подтверждена, вход может быть запрещён (REQUIRE_EMAIL_VERIFICATION).
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Деактивированный пользователь не может войти, его токены отклоняются.
+	DeactivatedAt *time.Time `gorm:"index" json:"deactivated_at"`
+	// Служебный пользователь для интеграций: входит только по API-ключу.
+	IsService bool `gorm:"not null;default:false" json:"is_service"`
+}
+
+// PublicUser — то, что видно о пользователе другим: в списках и во вложенных
+// связях (исполнитель задачи, автор дефекта, участник проекта). Связи моделей
+// объявлены через PublicUser, поэтому почта и служебные поля в них не
+// загружаются. Теги колонок совпадают с User: AutoMigrate подтягивает
+// PublicUser как зависимость и не должен менять таблицу users.
+type PublicUser struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	FullName string `gorm:"not null;index" json:"full_name"`
+	Role     Role   `gorm:"type:varchar(20);not null;index" json:"role"`
+}
+
+func (PublicUser) TableName() string {
+	return "users"
+}
+
+// UserProfile — полная карточка пользователя, только для администраторов.
+type UserProfile struct {
+	ID              uint       `json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	FullName        string     `json:"full_name"`
+	Email           string     `json:"email"`
+	Role            Role       `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	DeactivatedAt   *time.Time `json:"deactivated_at"`
+	IsService       bool       `json:"is_service"`
+}
+
+func (u User) Public() PublicUser {
+	return PublicUser{ID: u.ID, FullName: u.FullName, Role: u.Role}
+}
+
+func (u User) Profile() UserProfile {
+	return UserProfile{
+		ID:              u.ID,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+		FullName:        u.FullName,
+		Email:           u.Email,
+		Role:            u.Role,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		DeactivatedAt:   u.DeactivatedAt,
+		IsService:       u.IsService,
+	}
 }
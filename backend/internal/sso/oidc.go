@@ -0,0 +1,198 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"controlSystem/internal/models"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var ErrNoIDToken = errors.New("token response has no id_token")
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL — адрес OIDCCallbackHandler, зарегистрированный у IdP.
+	RedirectURL string
+	Scopes      []string
+
+	// GroupsClaim — claim ID-токена со списком групп (обычно "groups").
+	GroupsClaim string
+	// RoleMapping: группа IdP → роль. Если подходят несколько групп,
+	// берётся роль с наибольшими правами.
+	RoleMapping map[string]models.Role
+	// DefaultRole выдаётся, если ни одна группа не подошла. Пустая строка —
+	// таким пользователям вход запрещён.
+	DefaultRole models.Role
+	// SyncRoles — при каждом входе приводить роль к той, что следует из групп.
+	SyncRoles bool
+}
+
+// Identity — данные пользователя из проверенного ID-токена.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// Provider — OIDC-клиент (authorization code + PKCE). Discovery выполняется
+// при первом обращении, чтобы недоступность IdP не мешала запуску сервера.
+type Provider struct {
+	cfg Config
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// New возвращает nil, если SSO не настроен.
+func New(cfg Config) *Provider {
+	if cfg.Issuer == "" || cfg.ClientID == "" {
+		return nil
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"profile", "email"}
+	}
+	return &Provider{cfg: cfg}
+}
+
+func (p *Provider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, p.cfg.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, p.cfg.Scopes...),
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.cfg.ClientID})
+	return p.oauth, p.verifier, nil
+}
+
+// AuthURL — адрес страницы входа IdP. verifier — PKCE code_verifier,
+// который нужно сохранить до callback.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	oc, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oc.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange обменивает code на токены, проверяет подпись, аудиторию и nonce
+// ID-токена и возвращает данные пользователя.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	oc, v, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	tok, err := oc.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("code exchange: %w", err)
+	}
+	raw, ok := tok.Extra("id_token").(string)
+	if !ok || raw == "" {
+		return Identity{}, ErrNoIDToken
+	}
+	idToken, err := v.Verify(ctx, raw)
+	if err != nil {
+		return Identity{}, fmt.Errorf("id token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id token: nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, err
+	}
+	id := Identity{Issuer: idToken.Issuer, Subject: idToken.Subject}
+	id.Email, _ = claims["email"].(string)
+	id.EmailVerified, _ = claims["email_verified"].(bool)
+	id.Name, _ = claims["name"].(string)
+	id.Groups = stringList(claims[p.cfg.GroupsClaim])
+	return id, nil
+}
+
+// rolePriority — чем больше, тем шире права.
+var rolePriority = map[models.Role]int{
+	models.RoleCustomer: 1,
+	models.RoleEngineer: 2,
+	models.RoleManager:  3,
+	models.RoleAdmin:    4,
+}
+
+// RoleFor сопоставляет группам пользователя роль. ok = false — вход запрещён.
+func (p *Provider) RoleFor(groups []string) (role models.Role, ok bool) {
+	for _, g := range groups {
+		if r, found := p.cfg.RoleMapping[g]; found && rolePriority[r] > rolePriority[role] {
+			role = r
+		}
+	}
+	if role != "" {
+		return role, true
+	}
+	return p.cfg.DefaultRole, p.cfg.DefaultRole != ""
+}
+
+func (p *Provider) SyncRoles() bool { return p.cfg.SyncRoles }
+
+// ParseRoleMapping разбирает "группа=роль,группа=роль".
+func ParseRoleMapping(s string) (map[string]models.Role, error) {
+	m := map[string]models.Role{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid role mapping %q", pair)
+		}
+		role := models.Role(strings.TrimSpace(pair[i+1:]))
+		if !role.Valid() {
+			return nil, fmt.Errorf("invalid role %q in mapping", role)
+		}
+		m[strings.TrimSpace(pair[:i])] = role
+	}
+	return m, nil
+}
+
+// stringList принимает claim в виде массива строк или одной строки.
+func stringList(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		out := make([]string, 0, len(t))
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// KeyConfig — откуда брать ключи подписи JWT.
+type KeyConfig struct {
+	// SigningKeyFile — PEM с закрытым ключом RSA или Ed25519, которым подписываются новые токены.
+	SigningKeyFile string
+	// SigningKeyID — kid ключа подписи; по умолчанию вычисляется из открытого ключа.
+	SigningKeyID string
+	// VerifyKeyFiles — ключи, которыми ещё можно проверять токены (предыдущие
+	// ключи при ротации). Элемент — путь к PEM или "kid=путь".
+	VerifyKeyFiles []string
+	// DevMode разрешает запуск без ключа: генерируется временный Ed25519-ключ.
+	DevMode bool
+}
+
+type verifyKey struct {
+	Method jwt.SigningMethod
+	Public crypto.PublicKey
+}
+
+var keys struct {
+	sync.RWMutex
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	verify        map[string]verifyKey
+}
+
+var errNoSigningKey = errors.New("JWT signing key is not configured")
+
+// InitSigningKeys загружает ключи подписи. Вне dev-режима без ключа запуск невозможен.
+func InitSigningKeys(cfg KeyConfig) error {
+	verify := map[string]verifyKey{}
+
+	var signer crypto.Signer
+	kid := cfg.SigningKeyID
+	if cfg.SigningKeyFile != "" {
+		key, err := loadPEMKey(cfg.SigningKeyFile)
+		if err != nil {
+			return fmt.Errorf("signing key: %w", err)
+		}
+		s, ok := key.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("signing key %s is not a private key", cfg.SigningKeyFile)
+		}
+		signer = s
+	} else {
+		if !cfg.DevMode {
+			return errNoSigningKey
+		}
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		signer = priv
+		log.Println("WARNING: JWT signing key is not configured, using an ephemeral dev key")
+	}
+
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return err
+	}
+	if kid == "" {
+		if kid, err = keyID(signer.Public()); err != nil {
+			return err
+		}
+	}
+	verify[kid] = verifyKey{Method: method, Public: signer.Public()}
+
+	for _, entry := range cfg.VerifyKeyFiles {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		vkid, path := "", entry
+		if i := strings.Index(entry, "="); i > 0 {
+			vkid, path = entry[:i], entry[i+1:]
+		}
+		key, err := loadPEMKey(path)
+		if err != nil {
+			return fmt.Errorf("verification key: %w", err)
+		}
+		if s, ok := key.(crypto.Signer); ok {
+			key = s.Public()
+		}
+		m, err := signingMethodFor(key)
+		if err != nil {
+			return fmt.Errorf("verification key %s: %w", path, err)
+		}
+		if vkid == "" {
+			if vkid, err = keyID(key); err != nil {
+				return err
+			}
+		}
+		if _, dup := verify[vkid]; !dup {
+			verify[vkid] = verifyKey{Method: m, Public: key}
+		}
+	}
+
+	keys.Lock()
+	defer keys.Unlock()
+	keys.signingKID, keys.signingMethod, keys.signingKey, keys.verify = kid, method, signer, verify
+	return nil
+}
+
+// signToken подписывает токен текущим ключом и проставляет kid в заголовок.
+func signToken(claims jwt.Claims) (string, error) {
+	keys.RLock()
+	defer keys.RUnlock()
+	if keys.signingKey == nil {
+		return "", errNoSigningKey
+	}
+	token := jwt.NewWithClaims(keys.signingMethod, claims)
+	token.Header["kid"] = keys.signingKID
+	return token.SignedString(keys.signingKey)
+}
+
+// lookupVerifyKey выбирает ключ проверки по kid. Алгоритм берётся из ключа,
+// а не из заголовка токена, поэтому подменить его (например, на HS256) нельзя.
+func lookupVerifyKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	keys.RLock()
+	k, ok := keys.verify[kid]
+	keys.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != k.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return k.Public, nil
+}
+
+// JWK — открытый ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS возвращает все действующие ключи проверки — для /.well-known/jwks.json.
+func JWKS() []JWK {
+	keys.RLock()
+	defer keys.RUnlock()
+
+	out := make([]JWK, 0, len(keys.verify))
+	// Ключ подписи первым, остальные — ключи, оставленные для ротации.
+	if k, ok := keys.verify[keys.signingKID]; ok {
+		out = append(out, toJWK(keys.signingKID, k))
+	}
+	for kid, k := range keys.verify {
+		if kid != keys.signingKID {
+			out = append(out, toJWK(kid, k))
+		}
+	}
+	return out
+}
+
+func toJWK(kid string, k verifyKey) JWK {
+	j := JWK{Kid: kid, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.Public.(type) {
+	case *rsa.PublicKey:
+		j.Kty = "RSA"
+		j.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		j.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		j.Kty = "OKP"
+		j.Crv = "Ed25519"
+		j.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+	return j
+}
+
+func signingMethodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T, need RSA or Ed25519", pub)
+}
+
+// keyID — kid по умолчанию: префикс SHA-256 от открытого ключа в DER,
+// стабилен между перезапусками.
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func loadPEMKey(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block", path)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+}
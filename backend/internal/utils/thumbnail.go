@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/disintegration/imaging"
+)
+
+const (
+	ThumbnailSize = 320
+	PreviewSize   = 1280
+	// MaxImagePixels — предел размера изображения, которое ещё декодируется
+	// для превью. Декодированная картинка занимает ~4 байта на пиксель.
+	MaxImagePixels = 40_000_000
+)
+
+// ErrImageTooLarge — изображение превышает MaxImagePixels; файл сохраняется без превью.
+var ErrImageTooLarge = errors.New("image exceeds pixel budget")
+
+// ImageVariants декодирует JPEG/PNG с учётом EXIF-ориентации и возвращает
+// уменьшенные копии для списка (thumbnail) и просмотра (preview).
+// Формат копий совпадает с исходным, чтобы PNG не терял прозрачность.
+// Изображения меньше целевого размера не увеличиваются. Размеры читаются
+// из заголовка до декодирования: картинки больше MaxImagePixels
+// отклоняются с ErrImageTooLarge.
+func ImageVariants(r io.Reader, contentType string) (thumb, preview []byte, err error) {
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, nil, fmt.Errorf("%w: %dx%d", ErrImageTooLarge, cfg.Width, cfg.Height)
+	}
+
+	img, err := imaging.Decode(io.MultiReader(&header, r), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	format := imaging.JPEG
+	if contentType == "image/png" {
+		format = imaging.PNG
+	}
+
+	encode := func(maxSide int) ([]byte, error) {
+		resized := imaging.Fit(img, maxSide, maxSide, imaging.Lanczos)
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, resized, format, imaging.JPEGQuality(80)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if thumb, err = encode(ThumbnailSize); err != nil {
+		return nil, nil, err
+	}
+	if preview, err = encode(PreviewSize); err != nil {
+		return nil, nil, err
+	}
+	return thumb, preview, nil
+}
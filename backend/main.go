@@ -3,9 +3,16 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"controlSystem/internal/handlers"
+	"controlSystem/internal/mailer"
 	"controlSystem/internal/middleware"
+	"controlSystem/internal/models"
+	"controlSystem/internal/sso"
+	"controlSystem/internal/storage"
+	"controlSystem/internal/utils"
 	"controlSystem/migrations"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,6 +21,17 @@ import (
 )
 
 func main() {
+	appEnv := getEnv("APP_ENV", "production")
+	err := utils.InitSigningKeys(utils.KeyConfig{
+		SigningKeyFile: getEnv("JWT_SIGNING_KEY_FILE", ""),
+		SigningKeyID:   getEnv("JWT_SIGNING_KEY_ID", ""),
+		VerifyKeyFiles: strings.Split(getEnv("JWT_VERIFY_KEY_FILES", ""), ","),
+		DevMode:        appEnv == "dev" || appEnv == "development",
+	})
+	if err != nil {
+		log.Fatal("failed to init jwt keys:", err)
+	}
+
 	dsn := getEnv("DATABASE_DSN", "host=localhost user=postgres password=1234 dbname=control_system port=5432 sslmode=disable")
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
@@ -22,55 +40,157 @@ func main() {
 
 	migrations.MigrateAndSeed(db)
 
+	store, err := storage.New(storage.Config{
+		Backend:     getEnv("STORAGE_BACKEND", "local"),
+		LocalDir:    getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+		S3Endpoint:  getEnv("S3_ENDPOINT", ""),
+		S3Region:    getEnv("S3_REGION", ""),
+		S3Bucket:    getEnv("S3_BUCKET", ""),
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+		S3UseSSL:    getEnv("S3_USE_SSL", "false") == "true",
+	})
+	if err != nil {
+		log.Fatal("failed to init storage:", err)
+	}
+
+	mail, err := mailer.New(mailer.Config{
+		Backend:      getEnv("MAIL_BACKEND", "log"),
+		From:         getEnv("MAIL_FROM", ""),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 587),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		Dir:          getEnv("MAIL_DIR", "./mail"),
+	})
+	if err != nil {
+		log.Fatal("failed to init mailer:", err)
+	}
+	accounts := handlers.AccountConfig{
+		Mailer:                   mail,
+		FrontendURL:              getEnv("FRONTEND_ORIGIN", "http://localhost:3000"),
+		RequireEmailVerification: getEnv("REQUIRE_EMAIL_VERIFICATION", "false") == "true",
+	}
+
+	roleMapping, err := sso.ParseRoleMapping(getEnv("OIDC_ROLE_MAPPING", ""))
+	if err != nil {
+		log.Fatal("invalid OIDC_ROLE_MAPPING:", err)
+	}
+	oidcProvider := sso.New(sso.Config{
+		Issuer:       getEnv("OIDC_ISSUER", ""),
+		ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+		Scopes:       strings.Fields(getEnv("OIDC_SCOPES", "profile email")),
+		GroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		RoleMapping:  roleMapping,
+		DefaultRole:  models.Role(getEnv("OIDC_DEFAULT_ROLE", "")),
+		SyncRoles:    getEnv("OIDC_SYNC_ROLES", "false") == "true",
+	})
+
 	r := gin.Default()
-    	r.MaxMultipartMemory = 8 << 20
+	r.MaxMultipartMemory = 8 << 20
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{getEnv("FRONTEND_ORIGIN", "http://localhost:3000")},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Authorization", "Content-Type"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Authorization", "Content-Type", middleware.APIKeyHeader},
+		ExposeHeaders:    []string{"X-Total-Count", "X-Page", "X-Limit"},
 		AllowCredentials: true,
 	}))
 
-	api := r.Group("/api")
-    {
-    r.GET("/uploads/:filename", func(c *gin.Context) {
-        filename := c.Param("filename")
-        path := "./uploads/" + filename
-        if _, err := os.Stat(path); os.IsNotExist(err) {
-            c.JSON(404, gin.H{"error": "File not found"})
-            return
-        }
-
-        c.FileAttachment(path, filename)
-    })
-    	api.POST("/register", handlers.RegisterHandler(db))
-    	api.POST("/login", handlers.LoginHandler(db))
-        api.POST("/defects", handlers.CreateDefectHandler(db))
-        api.GET("/tasks", handlers.GetAllTasks(db))
-        api.PUT("/tasks/:id/status", handlers.UpdateTaskStatus(db))
-        api.POST("/defects/comment", handlers.AddDefectCommentHandler(db))
-        api.POST("/defects/comment-with-file", handlers.AddDefectCommentWithFileHandler(db))
-        api.GET("/reports/tasks", handlers.GetTaskReports(db))
-    	api.GET("/projects", handlers.ListProjectsHandler(db))
-    	api.GET("/projects/:id", handlers.GetProjectByID(db))
-    	api.POST("/projects", handlers.CreateProjectHandler(db))
-    	api.GET("/defects/for-manager", handlers.GetDefectsForManager(db))
-    	api.POST("/defects/assign", handlers.AssignAndConvertHandler(db))
-    	api.GET("/defects/:id/history", handlers.GetDefectHistory(db))
-    	api.GET("/my-tasks", handlers.GetMyTasks(db))
-        api.POST("/defects/test-upload", handlers.TestFileUploadHandler(db))
-    	api.GET("/users", handlers.ListUsersHandler(db))
-    	api.GET("/engineers-summary", handlers.EngineersSummaryHandler(db))
-
-
-    }
+	r.GET("/.well-known/jwks.json", handlers.JWKSHandler())
+
+	public := r.Group("/api")
+	{
+		public.POST("/register", handlers.RegisterHandler(db, accounts))
+		public.POST("/login", handlers.LoginHandler(db, accounts))
+		public.POST("/verify-email", handlers.VerifyEmailHandler(db))
+		public.POST("/verify-email/resend", handlers.ResendVerificationHandler(db, accounts))
+		public.POST("/password/forgot", handlers.ForgotPasswordHandler(db, accounts))
+		public.POST("/password/reset", handlers.ResetPasswordHandler(db))
 
+		public.GET("/auth/oidc", handlers.OIDCConfigHandler(oidcProvider))
+		public.GET("/auth/oidc/login", handlers.OIDCLoginHandler(db, oidcProvider))
+		public.GET("/auth/oidc/callback", handlers.OIDCCallbackHandler(db, oidcProvider, accounts))
+		public.POST("/auth/oidc/exchange", handlers.OIDCExchangeHandler(db))
+		public.POST("/refresh", handlers.RefreshHandler(db))
+	}
 
 	auth := r.Group("/api")
-	auth.Use(middleware.AuthMiddleware())
+	auth.Use(middleware.AuthMiddleware(db))
 	{
 		auth.GET("/me", handlers.MeHandler(db))
+		auth.PATCH("/me", middleware.RequireUserSession(), handlers.UpdateMeHandler(db))
+		auth.PUT("/me/password", middleware.RequireUserSession(), handlers.ChangePasswordHandler(db))
+		auth.GET("/me/preferences", handlers.GetPreferencesHandler(db))
+		auth.PUT("/me/preferences", middleware.RequireUserSession(), handlers.UpdatePreferencesHandler(db))
+		auth.POST("/logout", middleware.RequireUserSession(), handlers.LogoutHandler(db))
+		auth.POST("/logout-all", middleware.RequireUserSession(), handlers.LogoutAllHandler(db))
+
+		auth.GET("/projects", middleware.RequirePermission(models.PermProjectsRead), handlers.ListProjectsHandler(db))
+		auth.GET("/projects/:id", middleware.RequirePermission(models.PermProjectsRead), handlers.GetProjectByID(db))
+		auth.POST("/projects", middleware.RequirePermission(models.PermProjectsWrite), handlers.CreateProjectHandler(db))
+		auth.PUT("/projects/:id", middleware.RequirePermission(models.PermProjectsWrite), handlers.UpdateProjectHandler(db))
+		auth.PATCH("/projects/:id", middleware.RequirePermission(models.PermProjectsWrite), handlers.UpdateProjectHandler(db))
+		auth.DELETE("/projects/:id", middleware.RequirePermission(models.PermProjectsWrite), handlers.DeleteProjectHandler(db))
+		auth.POST("/projects/:id/restore", middleware.RequirePermission(models.PermProjectsWrite), handlers.RestoreProjectHandler(db))
+		auth.POST("/projects/:id/archive", middleware.RequirePermission(models.PermProjectsWrite), handlers.ArchiveProjectHandler(db))
+		auth.POST("/projects/:id/unarchive", middleware.RequirePermission(models.PermProjectsWrite), handlers.UnarchiveProjectHandler(db))
+		auth.GET("/projects/:id/history", middleware.RequirePermission(models.PermDefectsHistory), handlers.ProjectHistoryHandler(db))
+		auth.GET("/projects/:id/members", middleware.RequirePermission(models.PermProjectsRead), handlers.ListProjectMembersHandler(db))
+		auth.POST("/projects/:id/members", middleware.RequirePermission(models.PermProjectsWrite), handlers.AddProjectMemberHandler(db))
+		auth.DELETE("/projects/:id/members/:userId", middleware.RequirePermission(models.PermProjectsWrite), handlers.RemoveProjectMemberHandler(db))
+
+		auth.POST("/defects", middleware.RequirePermission(models.PermDefectsWrite), handlers.CreateDefectHandler(db))
+		auth.GET("/defects/for-manager", middleware.RequirePermission(models.PermDefectsRead), handlers.GetDefectsForManager(db))
+		auth.POST("/defects/assign", middleware.RequirePermission(models.PermDefectsAssign), handlers.AssignAndConvertHandler(db))
+		auth.PUT("/defects/:id", middleware.RequirePermission(models.PermDefectsEdit), handlers.UpdateDefectHandler(db))
+		auth.PATCH("/defects/:id", middleware.RequirePermission(models.PermDefectsEdit), handlers.UpdateDefectHandler(db))
+		auth.DELETE("/defects/:id", middleware.RequirePermission(models.PermDefectsEdit), handlers.DeleteDefectHandler(db))
+		auth.POST("/defects/:id/restore", middleware.RequirePermission(models.PermDefectsEdit), handlers.RestoreDefectHandler(db))
+		auth.GET("/defects/:id/history", middleware.RequirePermission(models.PermDefectsHistory), handlers.GetDefectHistory(db))
+		auth.POST("/defects/comment", middleware.RequirePermission(models.PermDefectsComment), handlers.AddDefectCommentHandler(db))
+		auth.POST("/defects/comment-with-file", middleware.RequirePermission(models.PermDefectsComment), handlers.AddDefectCommentWithFileHandler(db, store))
+		auth.POST("/defects/test-upload", middleware.RequirePermission(models.PermDefectsComment), handlers.TestFileUploadHandler(db, store))
+
+		auth.GET("/files/:id", middleware.RequirePermission(models.PermFilesRead), handlers.DownloadDefectFileHandler(db, store))
+		auth.GET("/files/:id/url", middleware.RequirePermission(models.PermFilesRead), handlers.DefectFileURLHandler(db, store))
+		auth.GET("/defects/:id/files.zip", middleware.RequirePermission(models.PermFilesRead), handlers.ExportDefectFilesHandler(db, store))
+		auth.GET("/projects/:id/files.zip", middleware.RequirePermission(models.PermFilesRead), handlers.ExportProjectFilesHandler(db, store))
+		auth.GET("/files/:id/versions", middleware.RequirePermission(models.PermFilesRead), handlers.ListDefectFileVersionsHandler(db))
+		auth.POST("/files/:id/versions", middleware.RequirePermission(models.PermDefectsComment), handlers.ReplaceDefectFileHandler(db, store))
+		auth.DELETE("/files/:id", middleware.RequirePermission(models.PermDefectsComment), handlers.DeleteDefectFileHandler(db))
+
+		auth.GET("/tasks", middleware.RequirePermission(models.PermTasksRead), handlers.GetAllTasks(db))
+		auth.GET("/my-tasks", middleware.RequirePermission(models.PermTasksReadOwn), handlers.GetMyTasks(db))
+		auth.PUT("/tasks/:id", middleware.RequirePermission(models.PermTasksWrite), handlers.UpdateTaskHandler(db))
+		auth.PATCH("/tasks/:id", middleware.RequirePermission(models.PermTasksWrite), handlers.UpdateTaskHandler(db))
+		auth.DELETE("/tasks/:id", middleware.RequirePermission(models.PermTasksWrite), handlers.DeleteTaskHandler(db))
+		auth.POST("/tasks/:id/restore", middleware.RequirePermission(models.PermTasksWrite), handlers.RestoreTaskHandler(db))
+		auth.GET("/tasks/:id/history", middleware.RequirePermission(models.PermDefectsHistory), handlers.TaskHistoryHandler(db))
+		auth.PUT("/tasks/:id/status", middleware.RequirePermission(models.PermTasksStatus), handlers.UpdateTaskStatus(db))
+
+		auth.GET("/reports/tasks", middleware.RequirePermission(models.PermReportsRead), handlers.GetTaskReports(db))
+		auth.GET("/users", middleware.RequirePermission(models.PermUsersRead), handlers.ListUsersHandler(db))
+		auth.GET("/users/:id", middleware.RequirePermission(models.PermUsersManage), handlers.GetUserHandler(db))
+		auth.PATCH("/users/:id", middleware.RequirePermission(models.PermUsersManage), handlers.UpdateUserHandler(db, accounts))
+		auth.PUT("/users/:id/role", middleware.RequirePermission(models.PermUsersManage), handlers.ChangeUserRoleHandler(db))
+		auth.POST("/users/:id/deactivate", middleware.RequirePermission(models.PermUsersManage), handlers.DeactivateUserHandler(db))
+		auth.POST("/users/:id/reactivate", middleware.RequirePermission(models.PermUsersManage), handlers.ReactivateUserHandler(db))
+		auth.POST("/users/:id/reassign-tasks", middleware.RequirePermission(models.PermUsersManage), handlers.ReassignUserTasksHandler(db))
+		auth.GET("/users/:id/history", middleware.RequirePermission(models.PermUsersManage), handlers.UserHistoryHandler(db))
+
+		auth.GET("/invites", middleware.RequirePermission(models.PermInvitesManage), handlers.ListInvitesHandler(db))
+		auth.POST("/invites", middleware.RequirePermission(models.PermInvitesManage), handlers.CreateInviteHandler(db))
+		auth.DELETE("/invites/:id", middleware.RequirePermission(models.PermInvitesManage), handlers.RevokeInviteHandler(db))
+
+		auth.POST("/service-users", middleware.RequirePermission(models.PermAPIKeysManage), handlers.CreateServiceUserHandler(db))
+		auth.GET("/api-keys", middleware.RequirePermission(models.PermAPIKeysManage), handlers.ListAPIKeysHandler(db))
+		auth.POST("/api-keys", middleware.RequirePermission(models.PermAPIKeysManage), handlers.CreateAPIKeyHandler(db))
+		auth.DELETE("/api-keys/:id", middleware.RequirePermission(models.PermAPIKeysManage), handlers.RevokeAPIKeyHandler(db))
+		auth.GET("/search", middleware.RequirePermission(models.PermSearch), handlers.SearchHandler(db))
+		auth.GET("/engineers-summary", middleware.RequirePermission(models.PermRatingRead), handlers.EngineersSummaryHandler(db))
 	}
 
 	port := getEnv("PORT", "8080")
@@ -83,3 +203,10 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func getEnvInt(key string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return def
+}
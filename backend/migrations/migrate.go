@@ -3,6 +3,8 @@ package migrations
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"controlSystem/internal/models"
 	"controlSystem/internal/utils"
@@ -11,20 +13,35 @@ import (
 )
 
 func MigrateAndSeed(db *gorm.DB) {
-	if err := db.AutoMigrate(&models.User{}, &models.RoleCode{}); err != nil {
+	hadVerification := db.Migrator().HasColumn(&models.User{}, "email_verified_at")
+	// Раньше на роль был ровно один код (уникальный индекс по role).
+	if db.Migrator().HasIndex(&models.RoleCode{}, "idx_role_codes_role") {
+		if err := db.Migrator().DropIndex(&models.RoleCode{}, "idx_role_codes_role"); err != nil {
+			log.Println("failed to drop role code index:", err)
+		}
+	}
+	if err := db.AutoMigrate(&schemaMigration{}, &models.User{}, &models.RoleCode{}); err != nil {
 		log.Fatal("automigrate error:", err)
 	}
+	runOnce(db, "users_email_lower_unique", createEmailIndex)
+	runOnce(db, "mark_existing_users_verified", func(tx *gorm.DB) error {
+		// колонка уже была — значит, пользователи подтверждали почту сами
+		if hadVerification {
+			return nil
+		}
+		return markExistingUsersVerified(tx)
+	})
 
 	managerCode := getEnv("CODE_MANAGER", "111111")
 	engineerCode := getEnv("CODE_ENGINEER", "222222")
-	adminEmail := getEnv("ADMIN_EMAIL", "admin@example.com")
+	adminEmail := strings.ToLower(strings.TrimSpace(getEnv("ADMIN_EMAIL", "admin@example.com")))
 	adminPass := getEnv("ADMIN_PASSWORD", "Admin123!")
 
 	seedRoleCode(db, models.RoleManager, managerCode)
 	seedRoleCode(db, models.RoleEngineer, engineerCode)
 
 	var admin models.User
-	if err := db.Where("email = ?", adminEmail).First(&admin).Error; err != nil {
+	if err := db.Where("lower(email) = ?", adminEmail).First(&admin).Error; err != nil {
 
 		hashed, err := utils.HashPassword(adminPass)
 		if err != nil {
@@ -36,16 +53,124 @@ func MigrateAndSeed(db *gorm.DB) {
 			Password: hashed,
 			Role:     models.RoleAdmin,
 		}
+		now := time.Now()
+		admin.EmailVerifiedAt = &now
 		if err := db.Create(&admin).Error; err != nil {
 			log.Fatal("create admin error:", err)
 		}
 	}
-    db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{},
-                                                      		&models.Defect{},
-                                                      		&models.DefectFile{},
-                                                      		&models.DefectHistory{})
+	db.AutoMigrate(&models.User{}, &models.Project{}, &models.Task{},
+		&models.Defect{},
+		&models.DefectFile{},
+		&models.DefectHistory{},
+		&models.ProjectMember{},
+		&models.AuditLog{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.AccountToken{},
+		&models.AuthThrottle{},
+		&models.AuthFailure{},
+		&models.RoleCodeRedemption{},
+		&models.UserPreferences{},
+		&models.UserIdentity{},
+		&models.OIDCLoginState{},
+		&models.APIKey{})
+
+	runOnce(db, "create_search_indexes", createSearchIndexes)
+	runOnce(db, "backfill_project_members", backfillProjectMembers)
+}
+
+// schemaMigration отмечает разовую миграцию данных, которая уже выполнена.
+type schemaMigration struct {
+	Name      string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
 
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// runOnce выполняет migrate в транзакции и запоминает name, чтобы при
+// следующих запусках не повторять её. Если migrate упала, отметка не
+// ставится и миграция повторится при следующем старте.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) {
+	var done int64
+	if err := db.Model(&schemaMigration{}).Where("name = ?", name).Count(&done).Error; err != nil {
+		log.Println("failed to check migration", name+":", err)
+		return
+	}
+	if done > 0 {
+		return
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&schemaMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		log.Println("migration", name, "failed:", err)
+	}
+}
+
+// createEmailIndex запрещает адреса, отличающиеся только регистром: вход и
+// письма ищут пользователя по lower(email). Если такие дубли уже есть, индекс
+// не создастся — их нужно развести вручную, миграция повторится при старте.
+func createEmailIndex(db *gorm.DB) error {
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email))`).Error
+}
+
+// createSearchIndexes создаёт GIN-индексы для полнотекстового поиска (handlers.SearchHandler).
+func createSearchIndexes(db *gorm.DB) error {
+	indexes := []string{
+		`CREATE INDEX IF NOT EXISTS idx_defects_search ON defects
+			USING GIN (to_tsvector('russian', coalesce(title, '') || ' ' || coalesce(description, '')))`,
+		`CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks
+			USING GIN (to_tsvector('russian', coalesce(name, '') || ' ' || coalesce(description, '')))`,
+		`CREATE INDEX IF NOT EXISTS idx_defect_histories_search ON defect_histories
+			USING GIN (to_tsvector('russian', coalesce(action_text, '')))`,
+	}
+	for _, stmt := range indexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillProjectMembers добавляет в участники проекта его менеджера и
+// заказчика, а также инженеров, которым уже назначены задачи проекта.
+// Видимость проектов строится только по project_members.
+func backfillProjectMembers(db *gorm.DB) error {
+	backfills := []struct {
+		query string
+		role  models.ProjectRole
+	}{
+		{`INSERT INTO project_members (created_at, project_id, user_id, role)
+		SELECT now(), projects.id, projects.manager_id, ?
+		FROM projects JOIN users ON users.id = projects.manager_id
+		ON CONFLICT (project_id, user_id) DO NOTHING`, models.ProjectRoleManager},
+		{`INSERT INTO project_members (created_at, project_id, user_id, role)
+		SELECT now(), projects.id, projects.customer_id, ?
+		FROM projects JOIN users ON users.id = projects.customer_id
+		ON CONFLICT (project_id, user_id) DO NOTHING`, models.ProjectRoleCustomer},
+		{`INSERT INTO project_members (created_at, project_id, user_id, role)
+		SELECT DISTINCT now(), project_id, assignee_id, ?
+		FROM tasks
+		WHERE assignee_id IS NOT NULL AND deleted_at IS NULL
+		ON CONFLICT (project_id, user_id) DO NOTHING`, models.ProjectRoleEngineer},
+	}
+	for _, b := range backfills {
+		if err := db.Exec(b.query, b.role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// markExistingUsersVerified считает подтверждёнными всех, кто
+// зарегистрировался до появления подтверждения почты, чтобы включение
+// REQUIRE_EMAIL_VERIFICATION не закрыло им вход.
+func markExistingUsersVerified(db *gorm.DB) error {
+	return db.Exec(`UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL`).Error
 }
 
 func seedRoleCode(db *gorm.DB, role models.Role, code string) {
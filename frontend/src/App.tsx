@@ -2,6 +2,9 @@ import React from "react";
 import { BrowserRouter as Router, Routes, Route } from "react-router-dom";
 import Register from "./pages/Register/Register";
 import Login from "./pages/Login/Login";
+import VerifyEmail from "./pages/VerifyEmail/VerifyEmail";
+import ResetPassword from "./pages/ResetPassword/ResetPassword";
+import SsoCallback from "./pages/SsoCallback/SsoCallback";
 import ProtectedRoute from "./components/ProtectedRoute";
 import Homepage from "./pages/Homepage/Homepage";
 import ProjectsPage from "./pages/Projects/ProjectsPage";
@@ -20,6 +23,9 @@ function App() {
             <Routes>
                 <Route path="/register" element={<Register />} />
                 <Route path="/login" element={<Login />} />
+                <Route path="/verify-email" element={<VerifyEmail />} />
+                <Route path="/reset-password" element={<ResetPassword />} />
+                <Route path="/sso/callback" element={<SsoCallback />} />
 
                 <Route
                     path="/"
@@ -1,6 +1,6 @@
 import axios from "axios";
 
-const API_BASE = process.env.REACT_APP_API_BASE || "http://localhost:8080";
+export const API_BASE = process.env.REACT_APP_API_BASE || "http://localhost:8080";
 
 export const api = axios.create({
     baseURL: `${API_BASE}/api`,
@@ -15,3 +15,96 @@ api.interceptors.request.use((config) => {
     }
     return config;
 });
+
+// Access-токен живёт недолго. При 401 один раз обмениваем refresh-токен на
+// новую пару и повторяем запрос; параллельные запросы ждут общий refresh.
+let refreshing: Promise<string> | null = null;
+
+const refreshAccessToken = async (): Promise<string> => {
+    const refreshToken = localStorage.getItem("refresh_token");
+    if (!refreshToken) throw new Error("no refresh token");
+    const res = await axios.post(`${API_BASE}/api/refresh`, { refresh_token: refreshToken });
+    localStorage.setItem("token", res.data.token);
+    localStorage.setItem("refresh_token", res.data.refresh_token);
+    return res.data.token;
+};
+
+api.interceptors.response.use(
+    (res) => res,
+    async (error) => {
+        const original = error.config;
+        const url: string = original?.url || "";
+        if (
+            error.response?.status !== 401 ||
+            !original ||
+            original._retry ||
+            url.endsWith("/login") ||
+            url.endsWith("/refresh")
+        ) {
+            return Promise.reject(error);
+        }
+        original._retry = true;
+        try {
+            refreshing = refreshing || refreshAccessToken();
+            const token = await refreshing;
+            original.headers = original.headers || {};
+            original.headers.Authorization = `Bearer ${token}`;
+            return api(original);
+        } catch {
+            localStorage.clear();
+            window.location.href = "/login";
+            return Promise.reject(error);
+        } finally {
+            refreshing = null;
+        }
+    }
+);
+
+export const logout = async (allSessions = false) => {
+    try {
+        await api.post(allSessions ? "/logout-all" : "/logout");
+    } catch {
+        // Сессия могла уже истечь — локальные данные всё равно очищаем.
+    }
+    localStorage.clear();
+};
+
+// Вложения отдаются только авторизованным пользователям, поэтому обычная ссылка
+// не подходит: скачиваем файл с токеном и открываем его как blob.
+export const openDefectFile = async (fileId: number) => {
+    const res = await api.get(`/files/${fileId}`, { responseType: "blob" });
+    const url = URL.createObjectURL(res.data);
+    window.open(url, "_blank", "noreferrer");
+    setTimeout(() => URL.revokeObjectURL(url), 60_000);
+};
+
+// Списки на сервере постраничные: без page/limit отдаётся только первая
+// страница, общее число записей приходит в заголовке X-Total-Count.
+export const PAGE_SIZE = 50;
+const MAX_PAGE_SIZE = 200;
+
+export interface Page<T> {
+    items: T[];
+    total: number;
+}
+
+export const fetchPage = async <T,>(
+    url: string,
+    page: number,
+    params: Record<string, unknown> = {},
+    limit = PAGE_SIZE
+): Promise<Page<T>> => {
+    const res = await api.get<T[]>(url, { params: { ...params, page, limit } });
+    const total = Number(res.headers["x-total-count"]);
+    return { items: res.data, total: Number.isNaN(total) ? res.data.length : total };
+};
+
+// Для выпадающих списков нужен весь справочник — выбираем его по страницам.
+export const fetchAll = async <T,>(url: string, params: Record<string, unknown> = {}): Promise<T[]> => {
+    const items: T[] = [];
+    for (let page = 1; ; page++) {
+        const res = await fetchPage<T>(url, page, params, MAX_PAGE_SIZE);
+        items.push(...res.items);
+        if (res.items.length === 0 || items.length >= res.total) return items;
+    }
+};
@@ -4,14 +4,15 @@ import { LogoutOutlined } from "@ant-design/icons";
 import { useNavigate } from "react-router-dom";
 import styles from "./AppHeader.module.css";
 import logo from "../../assets/logo.png";
+import { logout } from "../../api/api";
 
 const { Header } = Layout;
 
 const AppHeader: React.FC = () => {
     const navigate = useNavigate();
 
-    const handleLogout = () => {
-        localStorage.clear();
+    const handleLogout = async () => {
+        await logout();
         navigate("/login");
     };
 
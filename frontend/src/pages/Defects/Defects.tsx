@@ -3,7 +3,7 @@ import {Card, Button, Select, DatePicker, message, Tag, Typography, List, Layout
 import dayjs from "dayjs";
 import AppHeader from "../../components/AppHeader/AppHeader";
 import AppSidebar from "../../components/AppSidebar/AppSidebar";
-import { api } from "../../api/api";
+import { api, fetchAll, fetchPage, openDefectFile, PAGE_SIZE } from "../../api/api";
 import styles from "../../main.module.css";
 import {Content} from "antd/es/layout/layout";
 
@@ -38,17 +38,21 @@ const statusColors: Record<string, string> = {
 
 const DefectsPage: React.FC = () => {
     const [defects, setDefects] = useState<Defect[]>([]);
+    const [page, setPage] = useState(1);
+    const [total, setTotal] = useState(0);
     const [engineers, setEngineers] = useState<User[]>([]);
     const [assignees, setAssignees] = useState<{ [key: number]: number }>({});
     const [dueDates, setDueDates] = useState<{ [key: number]: any }>({});
 
     useEffect(() => {
-        api.get("/defects/for-manager")
-            .then(res => setDefects(res.data))
+        fetchPage<Defect>("/defects/for-manager", page)
+            .then(res => { setDefects(res.items); setTotal(res.total); })
             .catch(() => message.error("Не удалось загрузить дефекты"));
+    }, [page]);
 
-        api.get("/users?role=engineer")
-            .then(res => setEngineers(res.data))
+    useEffect(() => {
+        fetchAll<User>("/users", { role: "engineer" })
+            .then(setEngineers)
             .catch(() => message.error("Не удалось загрузить инженеров"));
     }, []);
 
@@ -71,6 +75,7 @@ const DefectsPage: React.FC = () => {
 
             message.success("Дефект преобразован в задачу!");
             setDefects(defects.filter(d => d.id !== defectId));
+            setTotal(prev => prev - 1);
         } catch {
             message.error("Ошибка при назначении дефекта");
         }
@@ -86,6 +91,7 @@ const DefectsPage: React.FC = () => {
                     <Typography.Title level={2}>Дефекты</Typography.Title>
                     <List
                         dataSource={defects}
+                        pagination={{ current: page, pageSize: PAGE_SIZE, total, onChange: setPage, showSizeChanger: false }}
                         renderItem={defect => (
                             <List.Item style={{ marginBottom: 16 }}>
                                 <Card title={defect.title} style={{ width: "100%" }}>
@@ -112,15 +118,11 @@ const DefectsPage: React.FC = () => {
                                             <List
                                                 size="small"
                                                 dataSource={defect.files}
-                                                renderItem={file => {
-                                                    const fileUrl = `http://localhost:8080/uploads/${encodeURIComponent(file.file_name)}`;
-
-                                                    return (
-                                                        <List.Item>
-                                                            <Link href={fileUrl} target="_blank">{file.file_name}</Link>
-                                                        </List.Item>
-                                                    );
-                                                }}
+                                                renderItem={file => (
+                                                    <List.Item>
+                                                        <Link onClick={() => openDefectFile(file.id)}>{file.file_name}</Link>
+                                                    </List.Item>
+                                                )}
                                             />
                                         </Paragraph>
                                     )}
@@ -1,10 +1,17 @@
-import React, { useState } from "react";
+import React, { useEffect, useState } from "react";
 import { Form, Input, Button, Row, Col, Typography, Card, message } from "antd";
-import { Link, useNavigate } from "react-router-dom";
-import { api } from "../../api/api";
+import { Link, useNavigate, useSearchParams } from "react-router-dom";
+import { api, API_BASE } from "../../api/api";
 
 const { Title, Text } = Typography;
 
+const ssoErrors: Record<string, string> = {
+    denied: "Вход через корпоративный аккаунт отменён",
+    no_role: "Для вашей группы не назначена роль в системе",
+    email_unverified: "Почта в корпоративном аккаунте не подтверждена",
+    account_deactivated: "Учётная запись деактивирована",
+};
+
 interface LoginFormValues {
     email: string;
     password: string;
@@ -13,6 +20,18 @@ interface LoginFormValues {
 const Login: React.FC = () => {
     const [loading, setLoading] = useState(false);
     const navigate = useNavigate();
+    const [params] = useSearchParams();
+    const [ssoEnabled, setSsoEnabled] = useState(false);
+
+    useEffect(() => {
+        api.get("/auth/oidc")
+            .then((res) => setSsoEnabled(Boolean(res.data.enabled)))
+            .catch(() => setSsoEnabled(false));
+        const ssoError = params.get("sso_error");
+        if (ssoError) {
+            message.error(ssoErrors[ssoError] || "Не удалось войти через корпоративный аккаунт");
+        }
+    }, [params]);
 
     const onFinish = async (values: LoginFormValues) => {
         setLoading(true);
@@ -23,6 +42,7 @@ const Login: React.FC = () => {
             });
             const loggedInUser = res.data.user;
             localStorage.setItem("token", res.data.token);
+            localStorage.setItem("refresh_token", res.data.refresh_token);
             localStorage.setItem("user_id", loggedInUser.id.toString());
             localStorage.setItem("role", loggedInUser.role);
             localStorage.setItem("user_full_name", loggedInUser.full_name);
@@ -30,6 +50,11 @@ const Login: React.FC = () => {
             message.success("Вы успешно вошли!");
             navigate("/");
         } catch (err: any) {
+            if (err?.response?.data?.code === "email_not_verified") {
+                message.warning("Почта не подтверждена. Мы отправили письмо со ссылкой ещё раз.");
+                api.post("/verify-email/resend", { email: values.email }).catch(() => {});
+                return;
+            }
             const text = err?.response?.data?.error || "Ошибка входа";
             message.error(text);
         } finally {
@@ -77,6 +102,18 @@ const Login: React.FC = () => {
                             </Button>
                         </Form.Item>
 
+                        {ssoEnabled && (
+                            <Form.Item>
+                                <Button block href={`${API_BASE}/api/auth/oidc/login`}>
+                                    Войти через корпоративный аккаунт
+                                </Button>
+                            </Form.Item>
+                        )}
+
+                        <Text style={{ display: "block", textAlign: "center", marginBottom: 8 }}>
+                            <Link to="/reset-password">Забыли пароль?</Link>
+                        </Text>
+
                         <Text style={{ display: "block", textAlign: "center" }}>
                             Нет аккаунта? <Link to="/register">Зарегистрируйтесь</Link>
                         </Text>
@@ -3,7 +3,7 @@ import {Layout, Spin, message, Typography, Button, Modal, Form, Input, Switch, S
 import AppHeader from "../../components/AppHeader/AppHeader";
 import AppSidebar from "../../components/AppSidebar/AppSidebar";
 import ProjectsList from "./components/ProjectList";
-import { api } from "../../api/api";
+import { api, fetchAll, fetchPage, PAGE_SIZE } from "../../api/api";
 import { useAuth, User as AuthUser } from "../../context/AuthContext";
 import styles from "../../main.module.css";
 
@@ -21,6 +21,8 @@ interface Project {
 
 const ProjectsPage: React.FC = () => {
     const [projects, setProjects] = useState<Project[]>([]);
+    const [page, setPage] = useState(1);
+    const [total, setTotal] = useState(0);
     const [customers, setCustomers] = useState<AuthUser[]>([]);
     const [loading, setLoading] = useState(true);
     const [isModalVisible, setIsModalVisible] = useState(false);
@@ -36,14 +38,7 @@ const ProjectsPage: React.FC = () => {
                     headers: { Authorization: `Bearer ${token}` },
                 });
                 setCurrentUser(userRes.data);
-                const res = await api.get("/projects", {
-                    headers: { Authorization: `Bearer ${token}` },
-                });
-                setProjects(res.data);
-                const customersRes = await api.get("/users?role=customer", {
-                    headers: { Authorization: `Bearer ${token}` },
-                });
-                setCustomers(customersRes.data);
+                setCustomers(await fetchAll<AuthUser>("/users", { role: "customer" }));
             } catch {
                 message.error("Не удалось загрузить данные");
             } finally {
@@ -53,6 +48,15 @@ const ProjectsPage: React.FC = () => {
         fetchData();
     }, [setCurrentUser]);
 
+    useEffect(() => {
+        fetchPage<Project>("/projects", page)
+            .then((res) => {
+                setProjects(res.items);
+                setTotal(res.total);
+            })
+            .catch(() => message.error("Не удалось загрузить проекты"));
+    }, [page]);
+
     const handleCreateProject = async (values: any) => {
         try {
             const token = localStorage.getItem("token");
@@ -70,6 +74,7 @@ const ProjectsPage: React.FC = () => {
             });
 
             setProjects((prev) => [...prev, res.data]);
+            setTotal((prev) => prev + 1);
             message.success("Проект успешно создан");
             form.resetFields();
             setIsModalVisible(false);
@@ -99,7 +104,10 @@ const ProjectsPage: React.FC = () => {
                             )}
                         </Flex>
 
-                        <ProjectsList projects={projects} />
+                        <ProjectsList
+                            projects={projects}
+                            pagination={{ current: page, pageSize: PAGE_SIZE, total, onChange: setPage, showSizeChanger: false }}
+                        />
 
                         <Modal
                             title="Создать проект"
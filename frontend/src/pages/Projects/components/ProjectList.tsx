@@ -1,5 +1,5 @@
 import React, { useState } from "react";
-import {Row, Col, Input, Select, Spin} from "antd";
+import {Row, Col, Input, Select, Spin, Pagination, PaginationProps} from "antd";
 import ProjectCard from "./ProjectCard";
 import {useAuth} from "../../../context/AuthContext";
 const { Search } = Input;
@@ -18,9 +18,10 @@ interface Project {
 
 interface Props {
     projects: Project[];
+    pagination?: PaginationProps;
 }
 
-const ProjectsList: React.FC<Props> = ({ projects }) => {
+const ProjectsList: React.FC<Props> = ({ projects, pagination }) => {
     const [searchTerm, setSearchTerm] = useState("");
     const [activeFilter, setActiveFilter] = useState<"all" | "active" | "inactive">("all");
     const { currentUser } = useAuth();
@@ -72,6 +73,7 @@ const ProjectsList: React.FC<Props> = ({ projects }) => {
                     </Col>
                 ))}
             </Row>
+            {pagination && <Pagination style={{ marginTop: 16 }} {...pagination} />}
         </div>
     );
 };
@@ -14,14 +14,18 @@ export const Register: React.FC = () => {
     const onFinish = async (values: any) => {
         setLoading(true);
         try {
-            await api.post("/register", {
+            const res = await api.post("/register", {
                 full_name: values.full_name,
                 email: values.email,
                 password: values.password,
                 role: values.role,
                 code: values.code || "",
             });
-            message.success("Регистрация прошла успешно. Войдите в аккаунт.");
+            message.success(
+                res.data.email_verification_required
+                    ? "Регистрация прошла успешно. Подтвердите почту по ссылке из письма."
+                    : "Регистрация прошла успешно. Войдите в аккаунт."
+            );
             window.location.href = "/login";
         } catch (err: any) {
             const text = err?.response?.data?.error || "Ошибка регистрации";
@@ -100,18 +104,16 @@ export const Register: React.FC = () => {
                             </Select>
                         </Form.Item>
 
-                        {role !== "customer" && (
-                            <Form.Item
-                                name="code"
-                                label="Код доступа (6 цифр)"
-                                rules={[
-                                    { required: true, message: "Введите код доступа" },
-                                    { pattern: /^\d{6}$/, message: "Код должен содержать ровно 6 цифр" },
-                                ]}
-                            >
-                                <Input placeholder="123456" maxLength={6} />
-                            </Form.Item>
-                        )}
+                        <Form.Item
+                            name="code"
+                            label={role === "customer" ? "Код приглашения (если есть)" : "Код приглашения"}
+                            rules={[
+                                { required: role !== "customer", message: "Введите код приглашения" },
+                                { pattern: /^[0-9A-Za-z]{6,12}$/, message: "Код состоит из 6–12 букв и цифр" },
+                            ]}
+                        >
+                            <Input placeholder="ABCD2345EFGH" maxLength={12} />
+                        </Form.Item>
 
                         <Form.Item>
                             <Button type="primary" htmlType="submit" block loading={loading}>
@@ -14,7 +14,7 @@ import {
 } from "antd";
 import { DownloadOutlined } from "@ant-design/icons";
 import dayjs from "dayjs";
-import { api } from "../../api/api";
+import { api, fetchAll } from "../../api/api";
 import * as XLSX from "xlsx";
 import {
     BarChart,
@@ -59,8 +59,8 @@ const ReportsPage: React.FC = () => {
         (localStorage.getItem("role") as "engineer" | "manager" | "customer") || "engineer";
 
     useEffect(() => {
-        api.get("/projects")
-            .then((res) => setProjects(res.data))
+        fetchAll<any>("/projects")
+            .then(setProjects)
             .catch(() => message.error("Не удалось загрузить проекты"));
     }, []);
 
@@ -78,9 +78,8 @@ const ReportsPage: React.FC = () => {
             return;
         }
 
-        api.get("/projects")
-            .then((res) => {
-                let allProjects = res.data;
+        fetchAll<any>("/projects")
+            .then((allProjects) => {
 
                 if (role === "manager") {
                     setProjects(allProjects);
@@ -0,0 +1,105 @@
+import React, { useState } from "react";
+import { Form, Input, Button, Row, Col, Typography, Card, message } from "antd";
+import { Link, useNavigate, useSearchParams } from "react-router-dom";
+import { api } from "../../api/api";
+
+const { Title, Text } = Typography;
+
+// Без ?token= страница запрашивает письмо со ссылкой, с токеном — задаёт новый пароль.
+const ResetPassword: React.FC = () => {
+    const [params] = useSearchParams();
+    const token = params.get("token");
+    const [loading, setLoading] = useState(false);
+    const navigate = useNavigate();
+
+    const requestLink = async (values: { email: string }) => {
+        setLoading(true);
+        try {
+            await api.post("/password/forgot", { email: values.email });
+            message.success("Если адрес зарегистрирован, на него отправлено письмо со ссылкой.");
+        } catch (err: any) {
+            message.error(err?.response?.data?.error || "Не удалось отправить письмо");
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    const resetPassword = async (values: { password: string }) => {
+        setLoading(true);
+        try {
+            await api.post("/password/reset", { token, password: values.password });
+            message.success("Пароль изменён. Войдите с новым паролем.");
+            navigate("/login");
+        } catch (err: any) {
+            message.error(err?.response?.data?.error || "Не удалось изменить пароль");
+        } finally {
+            setLoading(false);
+        }
+    };
+
+    return (
+        <Row style={{ minHeight: "100vh" }} align="middle" justify="center">
+            <Col xs={22} sm={16} md={12} lg={8}>
+                <Card bordered={false} style={{ borderRadius: 12, boxShadow: "0 4px 12px rgba(0,0,0,0.1)" }}>
+                    <div style={{ textAlign: "center", marginBottom: 24 }}>
+                        <Title level={3}>Восстановление пароля</Title>
+                        <Text type="secondary">
+                            {token ? "Придумайте новый пароль" : "Укажите почту, на которую зарегистрирован аккаунт"}
+                        </Text>
+                    </div>
+
+                    {token ? (
+                        <Form layout="vertical" onFinish={resetPassword}>
+                            <Form.Item
+                                name="password"
+                                label="Новый пароль"
+                                rules={[{ required: true, min: 6, message: "Минимум 6 символов" }]}
+                            >
+                                <Input.Password />
+                            </Form.Item>
+                            <Form.Item
+                                name="confirm"
+                                label="Повторите пароль"
+                                dependencies={["password"]}
+                                rules={[
+                                    { required: true, message: "Подтвердите пароль" },
+                                    ({ getFieldValue }) => ({
+                                        validator(_, value) {
+                                            return !value || getFieldValue("password") === value
+                                                ? Promise.resolve()
+                                                : Promise.reject(new Error("Пароли не совпадают"));
+                                        },
+                                    }),
+                                ]}
+                            >
+                                <Input.Password />
+                            </Form.Item>
+                            <Button type="primary" htmlType="submit" block loading={loading}>
+                                Сохранить пароль
+                            </Button>
+                        </Form>
+                    ) : (
+                        <Form layout="vertical" onFinish={requestLink}>
+                            <Form.Item
+                                name="email"
+                                label="Email"
+                                rules={[{ required: true, type: "email", message: "Введите корректный email" }]}
+                            >
+                                <Input placeholder="you@example.com" />
+                            </Form.Item>
+                            <Button type="primary" htmlType="submit" block loading={loading}>
+                                Отправить ссылку
+                            </Button>
+                        </Form>
+                    )}
+
+                    <Text style={{ display: "block", textAlign: "center", marginTop: 16 }}>
+                        <Link to="/login">Вернуться ко входу</Link>
+                    </Text>
+                </Card>
+            </Col>
+        </Row>
+    );
+};
+
+export default ResetPassword;
@@ -0,0 +1,47 @@
+import React, { useEffect, useRef } from "react";
+import { Row, Spin, message } from "antd";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { api } from "../../api/api";
+
+// Сюда backend возвращает пользователя после входа через IdP с одноразовым
+// ?code=, который обменивается на токены.
+const SsoCallback: React.FC = () => {
+    const [params] = useSearchParams();
+    const navigate = useNavigate();
+    const started = useRef(false);
+
+    useEffect(() => {
+        if (started.current) return;
+        started.current = true;
+
+        const code = params.get("code");
+        const redirect = params.get("redirect") || "/";
+        if (!code) {
+            navigate("/login");
+            return;
+        }
+        api.post("/auth/oidc/exchange", { code })
+            .then((res) => {
+                const user = res.data.user;
+                localStorage.setItem("token", res.data.token);
+                localStorage.setItem("refresh_token", res.data.refresh_token);
+                localStorage.setItem("user_id", user.id.toString());
+                localStorage.setItem("role", user.role);
+                localStorage.setItem("user_full_name", user.full_name);
+                // App читает токен при загрузке, поэтому переходим с перезагрузкой.
+                window.location.href = redirect.startsWith("/") && !redirect.startsWith("//") ? redirect : "/";
+            })
+            .catch((err) => {
+                message.error(err?.response?.data?.error || "Не удалось войти через корпоративный аккаунт");
+                navigate("/login");
+            });
+    }, [params, navigate]);
+
+    return (
+        <Row style={{ minHeight: "100vh" }} align="middle" justify="center">
+            <Spin size="large" />
+        </Row>
+    );
+};
+
+export default SsoCallback;
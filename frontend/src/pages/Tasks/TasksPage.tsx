@@ -18,7 +18,7 @@ import {
 import dayjs from "dayjs";
 import AppHeader from "../../components/AppHeader/AppHeader";
 import AppSidebar from "../../components/AppSidebar/AppSidebar";
-import { api } from "../../api/api";
+import { api, fetchPage, openDefectFile, PAGE_SIZE } from "../../api/api";
 import { UploadOutlined } from '@ant-design/icons';
 import {Content} from "antd/es/layout/layout";
 import styles from "../../main.module.css"
@@ -43,6 +43,8 @@ const statusOptions = ["Новая", "В работe", "На проверке",
 
 const TasksPage: React.FC = () => {
     const [tasks, setTasks] = useState<Task[]>([]);
+    const [page, setPage] = useState(1);
+    const [total, setTotal] = useState(0);
     const [searchTerm, setSearchTerm] = useState<string>("");
     const [filterStatus, setFilterStatus] = useState<string | null>(null);
 
@@ -55,9 +57,11 @@ const TasksPage: React.FC = () => {
 
     useEffect(() => {
         if (!userId || !userRole) { message.error("Пользователь не авторизован"); return; }
-        const url = userRole === "manager" ? "/tasks" : `/my-tasks?assignee_id=${userId}`;
-        api.get(url).then(res => setTasks(res.data)).catch(() => message.error("Не удалось загрузить задачи"));
-    }, [userId, userRole]);
+        const url = userRole === "manager" ? "/tasks" : "/my-tasks";
+        fetchPage<Task>(url, page, filterStatus ? { status: filterStatus } : {})
+            .then(res => { setTasks(res.items); setTotal(res.total); })
+            .catch(() => message.error("Не удалось загрузить задачи"));
+    }, [userId, userRole, page, filterStatus]);
 
     const handleStatusChange = async (taskId: number, newStatus: string) => {
         if (newStatus === "Закрыта" && userRole !== "manager") { message.warning("Только менеджер может закрывать задачи"); return; }
@@ -68,9 +72,16 @@ const TasksPage: React.FC = () => {
         } catch { message.error("Не удалось обновить статус задачи"); }
     };
 
+    // История дефекта не приходит в списке задач — подгружаем при раскрытии панели.
+    const loadDefectHistory = async (taskId: number, defectId: number) => {
+        try {
+            const res = await api.get(`/defects/${defectId}/history`);
+            setTasks(prev => prev.map(t => t.id === taskId && t.related_defect ? { ...t, related_defect: { ...t.related_defect, history: res.data } } : t));
+        } catch { message.error("Не удалось загрузить историю дефекта"); }
+    };
+
     const filteredTasks = tasks
         .filter(t => !searchTerm || t.name.toLowerCase().includes(searchTerm.toLowerCase()))
-        .filter(t => !filterStatus || t.status === filterStatus)
         .sort((a, b) => (a.status === "Закрыта" && b.status !== "Закрыта") ? 1 : (b.status === "Закрыта" && a.status !== "Закрыта") ? -1 : 0);
 
     return (
@@ -87,7 +98,7 @@ const TasksPage: React.FC = () => {
                             <Input placeholder="Поиск по названию задачи" value={searchTerm} onChange={e => setSearchTerm(e.target.value)} />
                         </Col>
                         <Col xs={24} sm={12} md={6}>
-                            <Select placeholder="Фильтр по статусу" allowClear value={filterStatus || undefined} onChange={val => setFilterStatus(val || null)} style={{ width: "100%" }}>
+                            <Select placeholder="Фильтр по статусу" allowClear value={filterStatus || undefined} onChange={val => { setFilterStatus(val || null); setPage(1); }} style={{ width: "100%" }}>
                                 {statusOptions.map(s => <Option key={s} value={s}>{s}</Option>)}
                             </Select>
                         </Col>
@@ -96,6 +107,7 @@ const TasksPage: React.FC = () => {
                     <List
                         dataSource={filteredTasks}
                         grid={{ gutter: 16, column: 1 }}
+                        pagination={{ current: page, pageSize: PAGE_SIZE, total, onChange: setPage, showSizeChanger: false }}
                         renderItem={task => (
                             <List.Item>
                                 <Card
@@ -124,10 +136,9 @@ const TasksPage: React.FC = () => {
                                                             <List
                                                                 size="small"
                                                                 dataSource={task.related_defect.files}
-                                                                renderItem={file => {
-                                                                    const fileUrl = `http://localhost:8080/uploads/${encodeURIComponent(file.file_name)}`;
-                                                                    return <List.Item><a href={fileUrl} target="_blank" rel="noreferrer">{file.file_name}</a></List.Item>;
-                                                                }}
+                                                                renderItem={file => (
+                                                                    <List.Item><a onClick={() => openDefectFile(file.id)}>{file.file_name}</a></List.Item>
+                                                                )}
                                                             />
                                                         </Paragraph>
                                                     )}
@@ -149,14 +160,18 @@ const TasksPage: React.FC = () => {
                                         </Col>
                                     </Row>
 
-                                    {task.related_defect?.history && (
+                                    {task.related_defect && (
                                         <>
                                             <Divider />
-                                            <Collapse>
-                                                <Collapse.Panel header={`История дефекта (${task.related_defect.history.length})`} key="history">
+                                            <Collapse onChange={keys => {
+                                                const defect = task.related_defect;
+                                                if (defect && !defect.history && keys.length > 0) loadDefectHistory(task.id, defect.id);
+                                            }}>
+                                                <Collapse.Panel header={task.related_defect.history ? `История дефекта (${task.related_defect.history.length})` : "История дефекта"} key="history">
                                                     <List
                                                         size="small"
-                                                        dataSource={task.related_defect.history}
+                                                        loading={!task.related_defect.history}
+                                                        dataSource={task.related_defect.history || []}
                                                         renderItem={h => (
                                                             <List.Item>
                                                                 <div>
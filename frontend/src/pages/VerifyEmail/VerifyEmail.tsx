@@ -0,0 +1,51 @@
+import React, { useEffect, useState } from "react";
+import { Row, Col, Card, Result, Spin, Button } from "antd";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { api } from "../../api/api";
+
+// Страница из ссылки в письме: подтверждает почту по ?token=.
+const VerifyEmail: React.FC = () => {
+    const [params] = useSearchParams();
+    const navigate = useNavigate();
+    const [status, setStatus] = useState<"loading" | "success" | "error">("loading");
+    const [error, setError] = useState("");
+
+    useEffect(() => {
+        const token = params.get("token");
+        if (!token) {
+            setStatus("error");
+            setError("В ссылке нет токена");
+            return;
+        }
+        api.post("/verify-email", { token })
+            .then(() => setStatus("success"))
+            .catch((err) => {
+                setStatus("error");
+                setError(err?.response?.data?.error || "Не удалось подтвердить почту");
+            });
+    }, [params]);
+
+    return (
+        <Row style={{ minHeight: "100vh" }} align="middle" justify="center">
+            <Col xs={22} sm={16} md={12} lg={8}>
+                <Card bordered={false} style={{ borderRadius: 12, boxShadow: "0 4px 12px rgba(0,0,0,0.1)" }}>
+                    {status === "loading" && <Spin style={{ display: "block", margin: "24px auto" }} />}
+                    {status !== "loading" && (
+                        <Result
+                            status={status}
+                            title={status === "success" ? "Почта подтверждена" : "Ошибка"}
+                            subTitle={status === "error" ? error : undefined}
+                            extra={
+                                <Button type="primary" onClick={() => navigate("/login")}>
+                                    Ко входу
+                                </Button>
+                            }
+                        />
+                    )}
+                </Card>
+            </Col>
+        </Row>
+    );
+};
+
+export default VerifyEmail;